@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
 )
 
 type S3Client interface {
@@ -17,6 +18,7 @@ type S3Client interface {
 	Upload(ctx context.Context, key string, body io.Reader) error
 	Download(ctx context.Context, key string) (io.ReadCloser, error)
 	DownloadWithPresignedURL(ctx context.Context, key string, lifetime time.Duration) (string, error)
+	Delete(ctx context.Context, key string) error
 }
 
 type s3Client struct {
@@ -77,6 +79,15 @@ func (s *s3Client) DownloadWithPresignedURL(ctx context.Context, key string, lif
 	return url.URL, nil
 }
 
+func (s *s3Client) Delete(ctx context.Context, key string) error {
+	_, err := s.S3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
+
 func NewS3ClientService(ctx context.Context, region, accessKey, secretKey, endpoint, bucket string) (S3Client, error) {
 	cfg, err := config.LoadDefaultConfig(
 		ctx,
@@ -87,6 +98,8 @@ func NewS3ClientService(ctx context.Context, region, accessKey, secretKey, endpo
 		return nil, err
 	}
 
+	otelaws.AppendMiddlewares(&cfg.APIOptions)
+
 	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
 		o.UsePathStyle = true
 		if endpoint != "" {
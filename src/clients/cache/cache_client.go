@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores opaque values by key. A miss is reported with ok=false and a nil error;
+// errors are meant to be logged and treated as misses by callers.
+type Cache interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+
+	// Ping reports whether the backing store is reachable.
+	Ping(ctx context.Context) error
+
+	// Close releases the connections to the backing store.
+	Close() error
+}
+
+type noopCache struct{}
+
+func (n *noopCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (n *noopCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (n *noopCache) Delete(ctx context.Context, keys ...string) error {
+	return nil
+}
+
+func (n *noopCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (n *noopCache) Close() error {
+	return nil
+}
+
+// NewNoopCache returns a cache that never stores anything.
+func NewNoopCache() Cache {
+	return &noopCache{}
+}
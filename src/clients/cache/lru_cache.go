@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+type lruCache struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List
+	size       int64
+	maxEntries int
+	maxBytes   int64
+}
+
+// NewLRUCache returns an in-process cache that evicts the least recently used entries
+// once either maxEntries or maxBytes is exceeded. A zero bound disables that bound.
+func NewLRUCache(maxEntries int, maxBytes int64) Cache {
+	return &lruCache{
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+	}
+}
+
+func (l *lruCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := element.Value.(*entry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		l.remove(element)
+		return nil, false, nil
+	}
+
+	l.order.MoveToFront(element)
+	return e.value, true, nil
+}
+
+func (l *lruCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		l.remove(element)
+	}
+
+	if l.maxBytes > 0 && int64(len(value)) > l.maxBytes {
+		return nil
+	}
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	l.items[key] = l.order.PushFront(&entry{key: key, value: value, expires: expires})
+	l.size += int64(len(value))
+
+	for (l.maxEntries > 0 && l.order.Len() > l.maxEntries) || (l.maxBytes > 0 && l.size > l.maxBytes) {
+		l.remove(l.order.Back())
+	}
+
+	return nil
+}
+
+func (l *lruCache) Delete(ctx context.Context, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := l.items[key]; ok {
+			l.remove(element)
+		}
+	}
+
+	return nil
+}
+
+func (l *lruCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (l *lruCache) Close() error {
+	return nil
+}
+
+func (l *lruCache) remove(element *list.Element) {
+	e := element.Value.(*entry)
+	l.order.Remove(element)
+	delete(l.items, e.key)
+	l.size -= int64(len(e.value))
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(10, 0)
+
+	_ = c.Set(ctx, "short", []byte("x"), 10*time.Millisecond)
+	_ = c.Set(ctx, "forever", []byte("y"), 0)
+
+	if _, ok, _ := c.Get(ctx, "short"); !ok {
+		t.Fatal("fresh entry missing")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Fatal("expired entry served")
+	}
+	if _, ok, _ := c.Get(ctx, "forever"); !ok {
+		t.Fatal("entry without TTL expired")
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(2, 0)
+
+	_ = c.Set(ctx, "a", []byte("a"), 0)
+	_ = c.Set(ctx, "b", []byte("b"), 0)
+	_, _, _ = c.Get(ctx, "a")
+	_ = c.Set(ctx, "c", []byte("c"), 0)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := c.Get(ctx, key); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestLRUCacheBytes(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(0, 10)
+
+	_ = c.Set(ctx, "a", make([]byte, 4), 0)
+	_ = c.Set(ctx, "b", make([]byte, 4), 0)
+	_ = c.Set(ctx, "c", make([]byte, 4), 0)
+
+	for key, want := range map[string]bool{"a": false, "b": true, "c": true} {
+		if _, ok, _ := c.Get(ctx, key); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+	}
+
+	// Values larger than the whole cache are not stored, and replace any older value.
+	_ = c.Set(ctx, "b", make([]byte, 11), 0)
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("oversized value cached")
+	}
+
+	_ = c.Set(ctx, "d", make([]byte, 6), 0)
+	if _, ok, _ := c.Get(ctx, "c"); !ok {
+		t.Error("c evicted although the cache fits c and d")
+	}
+
+	_ = c.Delete(ctx, "c", "d")
+	if _, ok, _ := c.Get(ctx, "d"); ok {
+		t.Error("deleted entry served")
+	}
+}
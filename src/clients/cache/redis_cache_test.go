@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisCache(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	c, err := NewRedisCache("redis://"+server.Addr()+"/0", "templates:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	if _, ok, err := c.Get(ctx, "invoice"); ok || err != nil {
+		t.Fatalf("empty cache: ok=%v err=%v", ok, err)
+	}
+
+	if err := c.Set(ctx, "invoice", []byte("body"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists("templates:invoice") {
+		t.Fatal("key is not prefixed")
+	}
+	if ttl := server.TTL("templates:invoice"); ttl != time.Minute {
+		t.Fatalf("ttl = %v, want 1m", ttl)
+	}
+
+	value, ok, err := c.Get(ctx, "invoice")
+	if err != nil || !ok || string(value) != "body" {
+		t.Fatalf("get = %q, %v, %v", value, ok, err)
+	}
+
+	server.FastForward(2 * time.Minute)
+	if _, ok, _ := c.Get(ctx, "invoice"); ok {
+		t.Fatal("expired entry served")
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Delete(ctx, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"a": false, "b": false, "c": true} {
+		if _, ok, _ := c.Get(ctx, key); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+	}
+	if err := c.Delete(ctx); err != nil {
+		t.Fatalf("empty delete: %v", err)
+	}
+
+	server.Close()
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if err := c.Ping(ctx); err == nil {
+		t.Fatal("ping succeeded without a server")
+	}
+	if _, _, err := c.Get(ctx, "c"); err == nil {
+		t.Fatal("get succeeded without a server")
+	}
+}
@@ -7,10 +7,14 @@ import (
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 type MongoClient interface {
 	GetDB() *mongo.Database
+	GetDatabase(name string) *mongo.Database
+	Ping(ctx context.Context) error
+	Disconnect(ctx context.Context) error
 }
 
 var (
@@ -25,7 +29,7 @@ type mongoClient struct {
 
 func NewMongoClient(ctx context.Context, uri, dbName string) (MongoClient, error) {
 	singleton.Do(func() {
-		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(otelmongo.NewMonitor()))
 		if err != nil {
 			panic(err)
 		}
@@ -50,3 +54,15 @@ func NewMongoClient(ctx context.Context, uri, dbName string) (MongoClient, error
 func (m *mongoClient) GetDB() *mongo.Database {
 	return m.database
 }
+
+func (m *mongoClient) GetDatabase(name string) *mongo.Database {
+	return m.client.Database(name)
+}
+
+func (m *mongoClient) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
+
+func (m *mongoClient) Disconnect(ctx context.Context) error {
+	return m.client.Disconnect(ctx)
+}
@@ -0,0 +1,46 @@
+package ocr
+
+import (
+	"context"
+	"image"
+	"io"
+	"sync"
+
+	// Decoders for the formats images reach OCR in.
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// FakeOCR recognises the same text in every image and records the format of each image it
+// was given. It stands in for tesseract in tests.
+type FakeOCR struct {
+	Text string
+
+	mu      sync.Mutex
+	formats []string
+}
+
+// NewFakeOCR returns an OCR recognising text in every image.
+func NewFakeOCR(text string) *FakeOCR {
+	return &FakeOCR{Text: text}
+}
+
+func (f *FakeOCR) Recognize(ctx context.Context, img io.Reader) (string, error) {
+	_, format, err := image.DecodeConfig(img)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.formats = append(f.formats, format)
+
+	return f.Text, ctx.Err()
+}
+
+// Images returns the format of every image recognised so far, in order.
+func (f *FakeOCR) Images() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.formats...)
+}
@@ -0,0 +1,22 @@
+package ocr
+
+import (
+	"context"
+	"io"
+)
+
+// OCR recognises the text of an image (PNG, JPEG, TIFF, ...).
+type OCR interface {
+	Recognize(ctx context.Context, image io.Reader) (string, error)
+}
+
+type noopOCR struct{}
+
+func (n *noopOCR) Recognize(ctx context.Context, image io.Reader) (string, error) {
+	return "", nil
+}
+
+// NewNoopOCR returns an OCR that recognises no text, leaving OCR disabled.
+func NewNoopOCR() OCR {
+	return &noopOCR{}
+}
@@ -0,0 +1,52 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type tesseractOCR struct {
+	Binary    string
+	Languages string
+	Timeout   time.Duration
+}
+
+// Recognize pipes image through the tesseract binary, reading the text from its stdout.
+func (t *tesseractOCR) Recognize(ctx context.Context, image io.Reader) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, t.Binary, "stdin", "stdout", "-l", t.Languages)
+	cmd.Stdin = image
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("tesseract timed out: %w", ctx.Err())
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("tesseract failed: %w: %s", err, detail)
+		}
+		return "", fmt.Errorf("tesseract failed: %w", err)
+	}
+
+	return stdout.String(), nil
+}
+
+// NewTesseractOCR runs the tesseract binary found at binary (or on PATH) with the given
+// "+" separated languages, e.g. "eng+ita".
+func NewTesseractOCR(binary, languages string, timeout time.Duration) OCR {
+	return &tesseractOCR{
+		Binary:    binary,
+		Languages: languages,
+		Timeout:   timeout,
+	}
+}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const chunkSize = 64 << 10
+
+type clamAVScanner struct {
+	Address string
+	Timeout time.Duration
+}
+
+// Scan streams body to clamd using the INSTREAM command of its TCP protocol.
+func (s *clamAVScanner) Scan(ctx context.Context, body io.Reader) (*ScanResult, error) {
+	dialer := net.Dialer{Timeout: s.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(s.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to send command to clamd: %w", err)
+	}
+
+	buf := make([]byte, chunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, werr := conn.Write(size); werr != nil {
+				return nil, streamError(conn, werr)
+			}
+			if _, werr := conn.Write(buf[:n]); werr != nil {
+				return nil, streamError(conn, werr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, streamError(conn, err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseReply(reply)
+}
+
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// streamError reports why streaming failed, preferring the reply clamd sends before
+// closing the connection, such as when the stream exceeds its size limit.
+func streamError(conn net.Conn, err error) error {
+	if reply, rerr := readReply(conn); rerr == nil && reply != "" {
+		if _, perr := parseReply(reply); perr != nil {
+			return perr
+		}
+	}
+	return fmt.Errorf("failed to stream file to clamd: %w", err)
+}
+
+// parseReply interprets replies such as "stream: OK", "stream: Eicar-Signature FOUND" or
+// "INSTREAM size limit exceeded. ERROR".
+func parseReply(reply string) (*ScanResult, error) {
+	if status, ok := strings.CutSuffix(reply, " ERROR"); ok {
+		return nil, fmt.Errorf("clamd error: %s", status)
+	}
+
+	_, status, ok := strings.Cut(reply, ": ")
+	if !ok {
+		return nil, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+
+	switch {
+	case status == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(status, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(status, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd error: %s", status)
+	}
+}
+
+func NewClamAVScanner(address string, timeout time.Duration) Scanner {
+	return &clamAVScanner{
+		Address: address,
+		Timeout: timeout,
+	}
+}
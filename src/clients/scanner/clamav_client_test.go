@@ -0,0 +1,147 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// clamd serves one INSTREAM session the way clamd does: it replies with reply once the
+// stream ends, or with the size limit error as soon as more than limit bytes arrive. It
+// sends the bytes received on the returned channel.
+func clamd(t *testing.T, limit int, reply func(received []byte) string) (string, <-chan []byte) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		command, err := r.ReadString(0)
+		if err != nil || command != "zINSTREAM\x00" {
+			conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+
+		var body bytes.Buffer
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(r, size); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			if _, err := io.CopyN(&body, r, int64(n)); err != nil {
+				return
+			}
+			if body.Len() > limit {
+				conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				received <- body.Bytes()
+				return
+			}
+		}
+
+		received <- body.Bytes()
+		conn.Write([]byte(reply(body.Bytes()) + "\x00"))
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestClamAVScanClean(t *testing.T) {
+	address, received := clamd(t, 1<<20, func([]byte) string { return "stream: OK" })
+	content := strings.Repeat("hello ", chunkSize/3)
+
+	result, err := NewClamAVScanner(address, 5*time.Second).Scan(context.Background(), strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Infected {
+		t.Fatalf("clean file reported infected: %+v", result)
+	}
+	if got := <-received; string(got) != content {
+		t.Fatalf("clamd received %d bytes, want %d", len(got), len(content))
+	}
+}
+
+func TestClamAVScanInfected(t *testing.T) {
+	address, _ := clamd(t, 1<<20, func(body []byte) string {
+		if bytes.Contains(body, []byte("EICAR")) {
+			return "stream: Eicar-Signature FOUND"
+		}
+		return "stream: OK"
+	})
+
+	result, err := NewClamAVScanner(address, 5*time.Second).Scan(context.Background(), strings.NewReader(eicar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Infected || result.Signature != "Eicar-Signature" {
+		t.Fatalf("result = %+v, want Eicar-Signature", result)
+	}
+}
+
+func TestClamAVScanSizeLimit(t *testing.T) {
+	address, _ := clamd(t, chunkSize, func([]byte) string { return "stream: OK" })
+
+	_, err := NewClamAVScanner(address, 5*time.Second).Scan(context.Background(), bytes.NewReader(make([]byte, 4*chunkSize)))
+	if err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Fatalf("err = %v, want the size limit error", err)
+	}
+}
+
+func TestClamAVScanError(t *testing.T) {
+	address, _ := clamd(t, 1<<20, func([]byte) string { return "stream: Can't allocate memory ERROR" })
+
+	_, err := NewClamAVScanner(address, 5*time.Second).Scan(context.Background(), strings.NewReader("hello"))
+	if err == nil || !strings.Contains(err.Error(), "allocate memory") {
+		t.Fatalf("err = %v, want the clamd error", err)
+	}
+}
+
+func TestClamAVScanUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	if _, err := NewClamAVScanner(address, time.Second).Scan(context.Background(), strings.NewReader("hello")); err == nil {
+		t.Fatal("scan succeeded without clamd")
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	for reply, want := range map[string]bool{
+		"stream: OK":                          true,
+		"stream: Eicar-Signature FOUND":       true,
+		"INSTREAM size limit exceeded. ERROR": false,
+		"stream: lstat() failed ERROR":        false,
+		"garbage":                             false,
+	} {
+		if _, err := parseReply(reply); (err == nil) != want {
+			t.Errorf("parseReply(%q) err = %v", reply, err)
+		}
+	}
+}
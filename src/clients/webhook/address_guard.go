@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for webhook targets resolving to an address webhooks may
+// not reach, such as loopback or private networks.
+var ErrForbiddenAddress = errors.New("webhook address is not allowed")
+
+// reserved lists the ranges not covered by the netip predicates that still never lead to a
+// public receiver: "this network", carrier-grade NAT, IETF protocol assignments, benchmarking,
+// reserved and NAT64, which may map to any IPv4 address.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// addressGuard keeps webhooks away from internal addresses, except for the allowed networks.
+type addressGuard struct {
+	allowed []netip.Prefix
+}
+
+func (g addressGuard) check(ip netip.Addr) error {
+	ip = ip.Unmap()
+
+	for _, prefix := range g.allowed {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+		}
+	}
+
+	return nil
+}
+
+// control checks every address dialled, once resolved, so that a host re-resolving to an
+// internal address after validation, or a redirect, cannot reach it.
+func (g addressGuard) control(network, address string, _ syscall.RawConn) error {
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	return g.check(addr.Addr())
+}
+
+// resolve checks every address host resolves to.
+func (g addressGuard) resolve(ctx context.Context, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return g.check(ip)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+
+	for _, addr := range addrs {
+		if err := g.check(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+type Notifier interface {
+	// Validate checks that url is an absolute http(s) URL whose host only resolves to
+	// addresses webhooks may reach, failing with ErrForbiddenAddress otherwise.
+	Validate(ctx context.Context, url string) error
+
+	// Notify posts payload to url, retrying failed deliveries. It returns the number of
+	// attempts made and the last error when every attempt failed.
+	Notify(ctx context.Context, url string, payload []byte) (int, error)
+}
+
+type httpNotifier struct {
+	Secret   []byte
+	Client   *http.Client
+	Attempts int
+	Backoff  time.Duration
+
+	guard addressGuard
+}
+
+// NewHTTPNotifier returns a notifier that signs each request with an HMAC-SHA256 of
+// "<timestamp>.<payload>" keyed by secret. Failed deliveries are attempted up to attempts
+// times, waiting backoff before the first retry and doubling it before each next one.
+// Deliveries never reach loopback, link-local, private or otherwise reserved addresses
+// outside the allowed networks; they are dialled directly, ignoring proxy settings, so that
+// the dialled address is the one checked.
+func NewHTTPNotifier(secret string, timeout time.Duration, attempts int, backoff time.Duration, allowed []netip.Prefix) Notifier {
+	guard := addressGuard{allowed: allowed}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   guard.control,
+	}).DialContext
+
+	return &httpNotifier{
+		Secret:   []byte(secret),
+		Client:   &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(transport)},
+		Attempts: max(1, attempts),
+		Backoff:  backoff,
+		guard:    guard,
+	}
+}
+
+func (n *httpNotifier) Validate(ctx context.Context, callback string) error {
+	u, err := url.Parse(callback)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("callbackUrl must be an absolute http(s) URL")
+	}
+	return n.guard.resolve(ctx, u.Hostname())
+}
+
+func (n *httpNotifier) Notify(ctx context.Context, url string, payload []byte) (int, error) {
+	wait := n.Backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = n.send(ctx, url, payload); err == nil || attempt == n.Attempts {
+			return attempt, err
+		}
+
+		select {
+		case <-time.After(wait):
+			wait *= 2
+		case <-ctx.Done():
+			return attempt, fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		}
+	}
+}
+
+func (n *httpNotifier) send(ctx context.Context, url string, payload []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(n.Secret, timestamp, payload))
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign returns the hex encoded signature of a payload sent at timestamp, so that receivers
+// written in Go can verify deliveries.
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAddressGuard(t *testing.T) {
+	guard := addressGuard{}
+
+	for address, allowed := range map[string]bool{
+		"93.184.215.14":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"::":              false,
+		"100.64.0.1":      false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	} {
+		err := guard.check(netip.MustParseAddr(address))
+		if allowed && err != nil {
+			t.Errorf("%s rejected: %v", address, err)
+		}
+		if !allowed && !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("%s allowed", address)
+		}
+	}
+
+	guard = addressGuard{allowed: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+	if err := guard.check(netip.MustParseAddr("10.1.2.3")); err != nil {
+		t.Errorf("allowed network rejected: %v", err)
+	}
+	if err := guard.check(netip.MustParseAddr("192.168.1.1")); err == nil {
+		t.Error("private address outside the allowed networks accepted")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	n := NewHTTPNotifier("secret", time.Second, 1, 0, nil)
+	ctx := context.Background()
+
+	for _, callback := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.8/hook",
+	} {
+		if err := n.Validate(ctx, callback); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("Validate(%s) = %v", callback, err)
+		}
+	}
+
+	for _, callback := range []string{"ftp://example.com/hook", "/hook", "http://"} {
+		if err := n.Validate(ctx, callback); err == nil || errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("Validate(%s) = %v, want a URL error", callback, err)
+		}
+	}
+
+	if err := n.Validate(ctx, "https://93.184.215.14/hook"); err != nil {
+		t.Errorf("public address rejected: %v", err)
+	}
+}
+
+// TestNotifyDialsOnlyAllowedAddresses checks the dialer itself, which also guards against
+// hosts re-resolving after validation and redirects.
+func TestNotifyDialsOnlyAllowedAddresses(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer server.Close()
+
+	blocked := NewHTTPNotifier("secret", time.Second, 1, 0, nil)
+	if _, err := blocked.Notify(context.Background(), server.URL, []byte(`{}`)); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Notify to loopback = %v, want ErrForbiddenAddress", err)
+	}
+	if received.Load() != 0 {
+		t.Fatal("loopback receiver was reached")
+	}
+
+	allowed := NewHTTPNotifier("secret", time.Second, 1, 0, []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+	if _, err := allowed.Notify(context.Background(), server.URL, []byte(`{}`)); err != nil {
+		t.Fatalf("Notify to an allowed network: %v", err)
+	}
+
+	// The receiver on 127.0.0.2 redirects to one outside the allowed network.
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("cannot listen on 127.0.0.2: %v", err)
+	}
+	redirect := httptest.NewUnstartedServer(http.RedirectHandler(server.URL, http.StatusTemporaryRedirect))
+	redirect.Listener.Close()
+	redirect.Listener = listener
+	redirect.Start()
+	defer redirect.Close()
+
+	received.Store(0)
+	only := NewHTTPNotifier("secret", time.Second, 1, 0, []netip.Prefix{netip.MustParsePrefix("127.0.0.2/32")})
+	if _, err := only.Notify(context.Background(), redirect.URL, []byte(`{}`)); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Notify through a redirect = %v, want ErrForbiddenAddress", err)
+	}
+	if received.Load() != 0 {
+		t.Fatal("redirect target was reached")
+	}
+}
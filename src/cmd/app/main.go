@@ -1,18 +1,30 @@
 package main
 
 import (
-	"log"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/antoniofrisenda/template-service/src/internal/api"
 	"github.com/antoniofrisenda/template-service/src/internal/config"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
 
 	"github.com/gofiber/fiber/v3"
 )
 
+var logger = logging.For("main")
+
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal(err)
+		logger.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if err := logging.Init(cfg.Logger.Level, cfg.Logger.Levels); err != nil {
+		logger.Error("Invalid log configuration", "error", err)
+		os.Exit(1)
 	}
 
 	app, err := api.Init(cfg)
@@ -20,7 +32,25 @@ func main() {
 		panic(err)
 	}
 
-	log.Fatal(app.Listen(":"+cfg.App.Port, fiber.ListenConfig{
-		DisableStartupMessage: true,
-	}))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := app.Listen(":"+cfg.App.Port, fiber.ListenConfig{
+			DisableStartupMessage: true,
+		}); err != nil {
+			logger.Error("Listen failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("Shutting down, draining in-flight requests...")
+
+	if err := app.ShutdownWithTimeout(cfg.App.ShutdownTimeout); err != nil {
+		logger.Error("Shutdown failed", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Shutdown complete")
 }
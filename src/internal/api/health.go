@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type HealthCheck struct {
+	Name  string
+	Probe func(ctx context.Context) error
+}
+
+type checkResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RegisterHealthRoutes exposes /livez, which only reports that the process serves
+// requests, and /readyz, which probes every dependency concurrently within timeout.
+// Readiness fails as soon as shutdown starts so load balancers stop routing traffic.
+func RegisterHealthRoutes(app *fiber.App, timeout time.Duration, checks ...HealthCheck) {
+	var shuttingDown atomic.Bool
+
+	app.Hooks().OnPreShutdown(func() error {
+		shuttingDown.Store(true)
+		return nil
+	})
+
+	live := func(c fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "ok"})
+	}
+
+	app.Get("/livez", live)
+	app.Get("/healthz", live)
+
+	app.Get("/readyz", func(c fiber.Ctx) error {
+		if shuttingDown.Load() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "shutting down"})
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), timeout)
+		defer cancel()
+
+		var (
+			mu      sync.Mutex
+			wg      sync.WaitGroup
+			ready   = true
+			results = make(map[string]checkResult, len(checks))
+		)
+
+		for _, check := range checks {
+			wg.Go(func() {
+				start := time.Now()
+				err := check.Probe(ctx)
+
+				result := checkResult{Status: "ok", Latency: time.Since(start).String()}
+				if err != nil {
+					result.Status = "error"
+					result.Error = err.Error()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				results[check.Name] = result
+				ready = ready && err == nil
+			})
+		}
+		wg.Wait()
+
+		status, code := "ok", fiber.StatusOK
+		if !ready {
+			status, code = "unavailable", fiber.StatusServiceUnavailable
+		}
+
+		return c.Status(code).JSON(fiber.Map{"status": status, "checks": results})
+	})
+}
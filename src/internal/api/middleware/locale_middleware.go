@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/antoniofrisenda/template-service/src/internal/locale"
+	"github.com/gofiber/fiber/v3"
+)
+
+// NewLocale stores the locales a request prefers in its context, read from the locale query
+// parameter or else from the Accept-Language header. Requests without either use the
+// default bodies of templates.
+func NewLocale() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		value := strings.TrimSpace(c.Query("locale"))
+		if value == "" {
+			value = strings.TrimSpace(c.Get(fiber.HeaderAcceptLanguage))
+		}
+
+		if value == "" {
+			return c.Next()
+		}
+
+		tags, err := locale.Parse(value)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		c.SetContext(locale.WithLocales(c.Context(), tags))
+		return c.Next()
+	}
+}
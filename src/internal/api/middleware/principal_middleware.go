@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
+	"github.com/antoniofrisenda/template-service/src/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+// NewPrincipal stores the user of every request and their permissions in the request context.
+// When claims are configured they are read from the verified bearer token, which is then
+// required, and the headers are ignored. Otherwise the headers are read only when trusted as
+// set by the gateway; requests are anonymous without either. Requests with `?preview=true`
+// see documents that are not published, which requires the preview permission.
+func NewPrincipal(cfg config.AuthConfig, verifier auth.TokenVerifier) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var principal auth.Principal
+
+		switch {
+		case cfg.SubjectClaim != "" || cfg.PermissionsClaim != "":
+			claims, err := verifier.Verify(c.Get(fiber.HeaderAuthorization))
+			if err != nil {
+				return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+			}
+
+			if cfg.SubjectClaim != "" {
+				principal.Subject, _ = claims[cfg.SubjectClaim].(string)
+			}
+			if cfg.PermissionsClaim != "" {
+				principal.Permissions = claimList(claims[cfg.PermissionsClaim])
+			}
+
+		case cfg.TrustHeaders:
+			principal.Subject = strings.TrimSpace(c.Get(cfg.UserHeader))
+			principal.Permissions = config.ParseList(c.Get(cfg.PermissionsHeader))
+		}
+
+		ctx := auth.WithPrincipal(c.Context(), principal)
+
+		if c.Query("preview") == "true" {
+			if !principal.Can(auth.PermissionPreview) {
+				return fiber.NewError(fiber.StatusForbidden, "preview requires the "+auth.PermissionPreview+" permission")
+			}
+			ctx = auth.WithPreview(ctx)
+		}
+
+		c.SetContext(ctx)
+		return c.Next()
+	}
+}
+
+// claimList reads a claim holding an array of strings or, like OAuth scopes, a space
+// separated string.
+func claimList(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
+	"github.com/antoniofrisenda/template-service/src/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+func principalApp(t *testing.T, cfg config.AuthConfig) *fiber.App {
+	t.Helper()
+	verifier, err := auth.NewTokenVerifier(tokenConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.UserHeader, cfg.PermissionsHeader = "X-User-ID", "X-User-Permissions"
+
+	app := fiber.New()
+	app.Use(NewPrincipal(cfg, verifier))
+	app.Get("/", func(c fiber.Ctx) error {
+		p := auth.FromContext(c.Context())
+		return c.SendString(p.Subject + "|" + strings.Join(p.Permissions, ","))
+	})
+	return app
+}
+
+func TestPrincipalClaims(t *testing.T) {
+	app := principalApp(t, config.AuthConfig{SubjectClaim: "sub", PermissionsClaim: "scope", TrustHeaders: true})
+	headers := map[string]string{"X-User-ID": "mallory", "X-User-Permissions": auth.PermissionPublish}
+
+	if status, _ := get(t, app, headers); status != fiber.StatusUnauthorized {
+		t.Fatalf("headers without token: status = %d, want 401", status)
+	}
+
+	headers["Authorization"] = "Bearer " + signedToken(`{"sub":"ann","scope":"templates:preview"}`)
+	status, body := get(t, app, headers)
+	if status != fiber.StatusOK || body != "ann|templates:preview" {
+		t.Fatalf("token with headers: %d %q, want the claims only", status, body)
+	}
+}
+
+func TestPrincipalHeaders(t *testing.T) {
+	headers := map[string]string{"X-User-ID": "ann", "X-User-Permissions": "templates:publish, templates:preview"}
+
+	trusted := principalApp(t, config.AuthConfig{TrustHeaders: true})
+	if status, body := get(t, trusted, headers); status != fiber.StatusOK || body != "ann|templates:publish,templates:preview" {
+		t.Fatalf("trusted headers: %d %q", status, body)
+	}
+
+	untrusted := principalApp(t, config.AuthConfig{})
+	if status, body := get(t, untrusted, headers); status != fiber.StatusOK || body != "|" {
+		t.Fatalf("untrusted headers: %d %q, want an anonymous request", status, body)
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
+	"github.com/antoniofrisenda/template-service/src/internal/config"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"github.com/gofiber/fiber/v3"
+)
+
+// NewTenant resolves the tenant of every request and stores it in the request context.
+// When a claim is configured the tenant comes only from the bearer token, whose signature
+// is verified: requests without a valid token, or whose token has no tenant, are rejected
+// and a header naming another tenant is forbidden. Otherwise the header, or the default
+// tenant, is used.
+func NewTenant(cfg config.TenantConfig, verifier auth.TokenVerifier) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ID := strings.TrimSpace(c.Get(cfg.Header))
+
+		if cfg.Claim != "" {
+			claims, err := verifier.Verify(c.Get(fiber.HeaderAuthorization))
+			if err != nil {
+				return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+			}
+
+			claim, _ := claims[cfg.Claim].(string)
+			if claim == "" {
+				return fiber.NewError(fiber.StatusUnauthorized, "bearer token has no "+cfg.Claim+" claim")
+			}
+			if ID != "" && ID != claim {
+				return fiber.NewError(fiber.StatusForbidden, "tenant header does not match token claim")
+			}
+			ID = claim
+		}
+
+		if ID == "" {
+			ID = cfg.Default
+		}
+
+		if ID == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "tenant is required: set the "+cfg.Header+" header")
+		}
+
+		if !tenant.IsValid(ID) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid tenant: "+ID)
+		}
+
+		c.SetContext(tenant.WithTenant(c.Context(), ID))
+		return c.Next()
+	}
+}
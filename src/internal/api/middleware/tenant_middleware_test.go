@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
+	"github.com/antoniofrisenda/template-service/src/internal/config"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"github.com/gofiber/fiber/v3"
+)
+
+var tokenConfig = config.TokenConfig{Secret: "s3cret", Issuer: "https://issuer.test", Audience: "template-service"}
+
+// signedToken signs the claims of payload, adding a valid issuer, audience and expiry.
+func signedToken(payload string) string {
+	claims := map[string]any{"iss": tokenConfig.Issuer, "aud": tokenConfig.Audience, "exp": time.Now().Add(time.Hour).Unix()}
+	if err := json.Unmarshal([]byte(payload), &claims); err != nil {
+		panic(err)
+	}
+	encoded, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(encoded)
+	mac := hmac.New(sha256.New, []byte(tokenConfig.Secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func tenantApp(t *testing.T, cfg config.TenantConfig) *fiber.App {
+	t.Helper()
+	verifier, err := auth.NewTokenVerifier(tokenConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(NewTenant(cfg, verifier))
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendString(tenant.Key(c.Context()))
+	})
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, headers map[string]string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestTenantClaim(t *testing.T) {
+	app := tenantApp(t, config.TenantConfig{Header: "X-Tenant-ID", Claim: "tenant", Default: "shared"})
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"tenant":"billing"}`)) + ".c2ln"
+
+	for name, tc := range map[string]struct {
+		headers map[string]string
+		status  int
+		tenant  string
+	}{
+		"header without token":  {map[string]string{"X-Tenant-ID": "billing"}, fiber.StatusUnauthorized, ""},
+		"no token, no header":   {nil, fiber.StatusUnauthorized, ""},
+		"unverified token":      {map[string]string{"Authorization": "Bearer " + forged}, fiber.StatusUnauthorized, ""},
+		"token without claim":   {map[string]string{"Authorization": "Bearer " + signedToken(`{"sub":"ann"}`)}, fiber.StatusUnauthorized, ""},
+		"header naming another": {map[string]string{"Authorization": "Bearer " + signedToken(`{"tenant":"billing"}`), "X-Tenant-ID": "hr"}, fiber.StatusForbidden, ""},
+		"valid token":           {map[string]string{"Authorization": "Bearer " + signedToken(`{"tenant":"billing"}`)}, fiber.StatusOK, "billing"},
+	} {
+		status, body := get(t, app, tc.headers)
+		if status != tc.status {
+			t.Errorf("%s: status = %d, want %d (%s)", name, status, tc.status, body)
+			continue
+		}
+		if tc.status == fiber.StatusOK && body != tc.tenant {
+			t.Errorf("%s: tenant = %q, want %q", name, body, tc.tenant)
+		}
+	}
+}
+
+func TestTenantHeader(t *testing.T) {
+	app := tenantApp(t, config.TenantConfig{Header: "X-Tenant-ID", Default: "shared"})
+
+	if status, body := get(t, app, map[string]string{"X-Tenant-ID": "billing"}); status != fiber.StatusOK || body != "billing" {
+		t.Errorf("header: %d %q", status, body)
+	}
+	if status, body := get(t, app, nil); status != fiber.StatusOK || body != "shared" {
+		t.Errorf("default: %d %q", status, body)
+	}
+}
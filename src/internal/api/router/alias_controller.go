@@ -0,0 +1,82 @@
+package router
+
+import (
+	"encoding/json"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/config"
+	"github.com/antoniofrisenda/template-service/src/internal/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+type AliasController interface {
+	PutAlias(c fiber.Ctx) error
+	GetAlias(c fiber.Ctx) error
+	DeleteAlias(c fiber.Ctx) error
+}
+
+type aliasController struct {
+	service service.AliasService
+}
+
+func NewAliasController(service service.AliasService) AliasController {
+	return &aliasController{service: service}
+}
+
+// PutAlias creates the Alias parameter or re-points it at the template given as target.
+func (a *aliasController) PutAlias(c fiber.Ctx) error {
+	name, err := aliasParam(c)
+	if err != nil {
+		return err
+	}
+
+	var payload dto.AliasRequest
+	if err := json.Unmarshal(c.Body(), &payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON payload: "+err.Error())
+	}
+
+	result, err := a.service.PutAlias(requestContext(c), name, &payload)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+func (a *aliasController) GetAlias(c fiber.Ctx) error {
+	name, err := aliasParam(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := a.service.FindAlias(requestContext(c), name)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Alias not found: "+err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+func (a *aliasController) DeleteAlias(c fiber.Ctx) error {
+	name, err := aliasParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := a.service.DeleteAlias(requestContext(c), name); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func aliasParam(c fiber.Ctx) (string, error) {
+	name := c.Params("Alias")
+	if err := config.ValidateAlias(name); err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return name, nil
+}
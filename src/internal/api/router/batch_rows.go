@@ -0,0 +1,131 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// parseRows reads the variable sets of a batch render: a JSON array, NDJSON or CSV body, or
+// a file uploaded as multipart form field "file" whose format is told by its extension. CSV
+// takes the variable names from its header and every value as a string.
+func parseRows(c fiber.Ctx, maxRows int) ([]map[string]any, error) {
+	header := c.Get("Content-Type")
+
+	var (
+		format string
+		body   io.Reader
+	)
+
+	switch {
+	case strings.HasPrefix(header, "multipart/form-data"):
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "File upload error: "+err.Error())
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "File upload error: "+err.Error())
+		}
+		defer reader.Close()
+
+		format, body = strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), ".")), reader
+	case strings.HasPrefix(header, "application/json"):
+		format, body = "json", bytes.NewReader(c.Body())
+	case strings.HasPrefix(header, "application/x-ndjson"):
+		format, body = "ndjson", bytes.NewReader(c.Body())
+	case strings.HasPrefix(header, "text/csv"):
+		format, body = "csv", bytes.NewReader(c.Body())
+	default:
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unsupported content type")
+	}
+
+	var (
+		rows []map[string]any
+		err  error
+	)
+
+	switch format {
+	case "json":
+		err = json.NewDecoder(body).Decode(&rows)
+	case "ndjson", "jsonl":
+		rows, err = parseNDJSON(body)
+	case "csv":
+		rows, err = parseCSV(body)
+	default:
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unsupported file format: "+format+" (must be json, ndjson or csv)")
+	}
+
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid rows: "+err.Error())
+	}
+	if len(rows) == 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "At least one row is required")
+	}
+	if maxRows > 0 && len(rows) > maxRows {
+		return nil, fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("Batch exceeds %d rows", maxRows))
+	}
+
+	return rows, nil
+}
+
+func parseNDJSON(body io.Reader) ([]map[string]any, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+
+	var rows []map[string]any
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var row map[string]any
+		if err := json.Unmarshal(text, &row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, scanner.Err()
+}
+
+func parseCSV(body io.Reader) ([]map[string]any, error) {
+	reader := csv.NewReader(body)
+
+	names, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range names {
+		names[i] = strings.TrimSpace(strings.TrimPrefix(names[i], "\ufeff"))
+	}
+
+	var rows []map[string]any
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(names))
+		for i, name := range names {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+}
@@ -1,13 +1,19 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"mime/multipart"
+	"strconv"
 	"strings"
 
 	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
 	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
 	"github.com/antoniofrisenda/template-service/src/internal/config"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
 	"github.com/antoniofrisenda/template-service/src/internal/service"
 	"github.com/gofiber/fiber/v3"
 )
@@ -15,13 +21,25 @@ import (
 type DocumentController interface {
 	GetTemplate(c fiber.Ctx) error
 	GetPresigned(c fiber.Ctx) error
+	ListTemplates(c fiber.Ctx) error
+	SearchTemplates(c fiber.Ctx) error
 	PostTemplate(c fiber.Ctx) error
+	PutTemplate(c fiber.Ctx) error
+	DeleteTemplate(c fiber.Ctx) error
+	PostTransition(c fiber.Ctx) error
+	PutLocale(c fiber.Ctx) error
+	DeleteLocale(c fiber.Ctx) error
 
 	GetLatestVariables(c fiber.Ctx) error
+	PostRender(c fiber.Ctx) error
+	PostRenderBatch(c fiber.Ctx) error
+	PostCompose(c fiber.Ctx) error
+	PostLint(c fiber.Ctx) error
 }
 
 type documentController struct {
 	service service.DocumentService
+	upload  config.UploadConfig
 }
 
 func (d *documentController) GetTemplate(c fiber.Ctx) error {
@@ -30,8 +48,11 @@ func (d *documentController) GetTemplate(c fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	result, err := d.service.FindTemplate(c.Context(), id)
+	result, err := d.service.FindTemplate(requestContext(c), id)
 	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
 		return fiber.NewError(fiber.StatusNotFound, "Template not found: "+err.Error())
 	}
 
@@ -44,60 +65,400 @@ func (d *documentController) GetPresigned(c fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	url, err := d.service.FindTemplateWithPresignedURL(c.Context(), id)
+	url, err := d.service.FindTemplateWithPresignedURL(requestContext(c), id)
 	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate presigned URL: "+err.Error())
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"url": url})
 }
 
-func (d *documentController) PostTemplate(c fiber.Ctx) error {
-	var (
-		payload *dto.InsertDocument
-		file    *multipart.FileHeader
-		err     error
-	)
-
-	header := c.Get("Content-Type")
+func (d *documentController) ListTemplates(c fiber.Ctx) error {
+	query, err := parseQuery(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
-	switch {
-	case header != "" && strings.HasPrefix(header, "multipart/form-data"):
-		payload, file, err = d.parseMultipart(c)
-	case header == "application/json":
-		payload, err = d.parseJSON(c)
-	default:
-		return fiber.NewError(fiber.StatusBadRequest, "Unsupported content type")
+	result, err := d.service.ListTemplates(requestContext(c), query)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+func (d *documentController) SearchTemplates(c fiber.Ctx) error {
+	query, err := parseQuery(c)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	if query.Cursor != "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Search results are not paginated")
+	}
+
+	result, err := d.service.SearchTemplates(requestContext(c), c.Query("q"), query)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// parseQuery reads the filters of ListTemplates: repeated or comma separated `tag`, `category`,
+// `metadata.<key>`, `type`, `source`, `contentType`, `state`, `limit` and `cursor`.
+func parseQuery(c fiber.Ctx) (*dto.DocumentQuery, error) {
+	query := &dto.DocumentQuery{
+		Category:    strings.TrimSpace(c.Query("category")),
+		Type:        model.DocumentType(c.Query("type")),
+		Source:      model.SourceType(c.Query("source")),
+		ContentType: model.ContentType(c.Query("contentType")),
+		State:       model.LifecycleState(c.Query("state")),
+		Cursor:      c.Query("cursor"),
+	}
+
+	args := c.RequestCtx().QueryArgs()
+	for _, value := range args.PeekMulti("tag") {
+		for _, tag := range config.ParseList(string(value)) {
+			query.Tags = append(query.Tags, strings.ToLower(tag))
+		}
+	}
+
+	for key, value := range args.All() {
+		name, ok := strings.CutPrefix(string(key), "metadata.")
+		if !ok {
+			continue
+		}
+		if name == "" || strings.ContainsAny(name, ".$") {
+			return nil, fmt.Errorf("invalid metadata filter: %q", key)
+		}
+		if query.Metadata == nil {
+			query.Metadata = map[string]string{}
+		}
+		query.Metadata[name] = string(value)
+	}
+
+	if query.Type != "" && !query.Type.IsValid() {
+		return nil, fmt.Errorf("invalid document type: %s", query.Type)
+	}
+	if query.Source != "" && !query.Source.IsValid() {
+		return nil, fmt.Errorf("invalid source type: %s", query.Source)
+	}
+	if query.ContentType != "" && !query.ContentType.IsValid() {
+		return nil, fmt.Errorf("invalid content type: %s", query.ContentType)
+	}
+	if query.State != "" && !query.State.IsValid() {
+		return nil, fmt.Errorf("invalid state: %s", query.State)
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid limit: %q", value)
+		}
+		query.Limit = limit
+	}
 
-	result, err := d.service.InsertTemplate(c.Context(), payload, file)
+	return query, nil
+}
+
+func (d *documentController) PostTemplate(c fiber.Ctx) error {
+	payload, file, err := d.parse(c)
 	if err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	if err := config.ValidateUpload(payload, file, d.upload); err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	result, err := d.service.InsertTemplate(requestContext(c), payload, file)
+	if report, ok := lintReport(err); ok {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	}
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(result)
 }
 
+func (d *documentController) PutTemplate(c fiber.Ctx) error {
+	id, err := d.getIDParam(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	payload, file, err := d.parse(c)
+	if err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	if err := config.ValidateUpload(payload, file, d.upload); err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	result, err := d.service.UpdateTemplate(requestContext(c), id, payload, file)
+	if report, ok := lintReport(err); ok {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	}
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// PutLocale stores the variant of a template for the Locale parameter, from a JSON body with
+// the contentType and body or a multipart form with the contentType, file and variables.
+func (d *documentController) PutLocale(c fiber.Ctx) error {
+	id, err := d.getIDParam(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	payload, file, err := d.parseVariant(c)
+	if err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	if err := config.ValidateUpload(payload, file, d.upload); err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	result, err := d.service.PutLocale(requestContext(c), id, c.Params("Locale"), payload, file)
+	if report, ok := lintReport(err); ok {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	}
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+func (d *documentController) DeleteLocale(c fiber.Ctx) error {
+	id, err := d.getIDParam(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := d.service.DeleteLocale(requestContext(c), id, c.Params("Locale")); err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (d *documentController) DeleteTemplate(c fiber.Ctx) error {
+	id, err := d.getIDParam(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := d.service.DeleteTemplate(requestContext(c), id); err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete template: "+err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (d *documentController) PostTransition(c fiber.Ctx) error {
+	id, err := d.getIDParam(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var payload dto.TransitionRequest
+	if err := json.Unmarshal(c.Body(), &payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON payload: "+err.Error())
+	}
+
+	result, err := d.service.TransitionTemplate(requestContext(c), id, &payload)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
 func (d *documentController) GetLatestVariables(c fiber.Ctx) error {
 	id, err := d.getIDParam(c)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	variables, err := d.service.ExtractVariables(c.Context(), id)
+	if c.Query("extended") == "true" {
+		return d.getLocatedVariables(c, id)
+	}
+
+	result, err := d.service.ExtractVariables(requestContext(c), id, c.Query("refresh") == "true")
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// getLocatedVariables writes the extended variables response, with the occurrences of each one.
+func (d *documentController) getLocatedVariables(c fiber.Ctx, id string) error {
+	result, err := d.service.LocateVariables(requestContext(c), id)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+func (d *documentController) PostRender(c fiber.Ctx) error {
+	id, err := d.getIDParam(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var payload dto.RenderRequest
+	if err := json.Unmarshal(c.Body(), &payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON payload: "+err.Error())
+	}
+
+	result, err := d.service.RenderTemplate(requestContext(c), id, payload.Variables)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, "Template not found: "+err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// PostRenderBatch renders a template once per row. The ZIP archive is streamed back, or with
+// ?output=url stored in S3 and linked from the JSON summary.
+func (d *documentController) PostRenderBatch(c fiber.Ctx) error {
+	id, err := d.getIDParam(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	rows, err := parseRows(c, int(d.upload.MaxBatchRows))
 	if err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	if c.Query("output") == "url" {
+		result, err := d.service.StoreBatch(requestContext(c), id, rows)
+		if err != nil {
+			if status, ok := serviceStatus(err); ok {
+				return fiber.NewError(status, err.Error())
+			}
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+
+		return c.Status(fiber.StatusOK).JSON(result)
+	}
+
+	result, archive, err := d.service.RenderBatch(requestContext(c), id, rows)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
 		return fiber.NewError(fiber.StatusNotFound, err.Error())
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"variables": variables})
+	c.Attachment(id + ".zip")
+	c.Set("X-Batch-Rows", strconv.Itoa(result.Rows))
+	c.Set("X-Batch-Rendered", strconv.Itoa(result.Rendered))
+	c.Set("X-Batch-Failed", strconv.Itoa(result.Failed))
+	return c.Status(fiber.StatusOK).SendStream(archive)
 }
 
-func NewDocumentController(service service.DocumentService) DocumentController {
-	return &documentController{service: service}
+// PostCompose merges the listed documents into a single PDF.
+func (d *documentController) PostCompose(c fiber.Ctx) error {
+	var payload dto.ComposeRequest
+	if err := json.Unmarshal(c.Body(), &payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON payload: "+err.Error())
+	}
+
+	if len(payload.Documents) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "At least one document is required")
+	}
+
+	result, err := d.service.ComposeDocuments(requestContext(c), &payload)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	return c.Status(fiber.StatusOK).Send(result)
+}
+
+// PostLint lints a template as PostTemplate would, without storing it.
+func (d *documentController) PostLint(c fiber.Ctx) error {
+	payload, file, err := d.parse(c)
+	if err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	if err := config.ValidateUpload(payload, file, d.upload); err != nil {
+		return asFiberError(err, fiber.StatusBadRequest)
+	}
+
+	report, err := d.service.LintTemplate(requestContext(c), payload, file)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+func NewDocumentController(service service.DocumentService, upload config.UploadConfig) DocumentController {
+	return &documentController{service: service, upload: upload}
+}
+
+// requestContext adds the matched route to the request context for log correlation.
+func requestContext(c fiber.Ctx) context.Context {
+	return logging.With(c.Context(),
+		slog.String("method", c.Method()),
+		slog.String("route", c.Route().Path),
+	)
 }
 
 func (d *documentController) getIDParam(c fiber.Ctx) (string, error) {
@@ -108,20 +469,75 @@ func (d *documentController) getIDParam(c fiber.Ctx) (string, error) {
 	return ID, nil
 }
 
+// parse reads the document payload from either a multipart form or a JSON body.
+func (d *documentController) parse(c fiber.Ctx) (*dto.InsertDocument, *multipart.FileHeader, error) {
+	header := c.Get("Content-Type")
+
+	switch {
+	case header != "" && strings.HasPrefix(header, "multipart/form-data"):
+		return d.parseMultipart(c)
+	case header == "application/json":
+		payload, err := d.parseJSON(c)
+		return payload, nil, err
+	default:
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Unsupported content type")
+	}
+}
+
 func (d *documentController) parseMultipart(c fiber.Ctx) (*dto.InsertDocument, *multipart.FileHeader, error) {
 	file, err := c.FormFile("file")
 	if err != nil {
 		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "File upload error: "+err.Error())
 	}
 
-	return &dto.InsertDocument{
+	declared, err := parseDeclarations(c.FormValue("variables"))
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid variables: "+err.Error())
+	}
+
+	var metadata map[string]string
+	if value := c.FormValue("metadata"); value != "" {
+		if err := json.Unmarshal([]byte(value), &metadata); err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid metadata: "+err.Error())
+		}
+	}
+
+	payload := &dto.InsertDocument{
 		Name:        c.FormValue("name"),
+		Slug:        c.FormValue("slug"),
 		Summary:     c.FormValue("summary"),
+		Tags:        config.ParseList(c.FormValue("tags")),
+		Category:    c.FormValue("category"),
+		Metadata:    metadata,
 		Type:        model.DocumentType(c.Params("DocumentType")),
 		Source:      model.SourceType("FILE"),
 		ContentType: model.ContentType(c.FormValue("contentType")),
-		Body:        &dto.InsertBody{},
-	}, file, nil
+		Body:        &dto.InsertBody{Variables: declared},
+	}
+
+	if err := config.Validate(payload); err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return payload, file, nil
+}
+
+// parseDeclarations reads the variables form field, either a JSON array of declarations or a
+// comma separated list of names.
+func parseDeclarations(value string) ([]dto.VariableDeclaration, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		var declared []dto.VariableDeclaration
+		if err := json.Unmarshal([]byte(value), &declared); err != nil {
+			return nil, err
+		}
+		return declared, nil
+	}
+
+	var declared []dto.VariableDeclaration
+	for _, name := range config.ParseList(value) {
+		declared = append(declared, dto.VariableDeclaration{Name: name})
+	}
+	return declared, nil
 }
 
 func (d *documentController) parseJSON(c fiber.Ctx) (*dto.InsertDocument, error) {
@@ -136,3 +552,103 @@ func (d *documentController) parseJSON(c fiber.Ctx) (*dto.InsertDocument, error)
 
 	return &payload, nil
 }
+
+// parseVariant reads a localized variant, which carries no name or summary of its own.
+func (d *documentController) parseVariant(c fiber.Ctx) (*dto.InsertDocument, *multipart.FileHeader, error) {
+	payload := &dto.InsertDocument{
+		Type:   model.DocumentType(c.Params("DocumentType")),
+		Source: model.SourceType(c.Params("SourceType")),
+	}
+	var file *multipart.FileHeader
+
+	switch header := c.Get("Content-Type"); {
+	case strings.HasPrefix(header, "multipart/form-data"):
+		var err error
+		if file, err = c.FormFile("file"); err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "File upload error: "+err.Error())
+		}
+
+		declared, err := parseDeclarations(c.FormValue("variables"))
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid variables: "+err.Error())
+		}
+
+		payload.ContentType = model.ContentType(c.FormValue("contentType"))
+		payload.Body = &dto.InsertBody{Variables: declared}
+
+	case header == "application/json":
+		var body dto.InsertDocument
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid JSON payload: "+err.Error())
+		}
+
+		payload.ContentType = body.ContentType
+		payload.Body = body.Body
+
+	default:
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Unsupported content type")
+	}
+
+	if err := config.ValidateBody(payload.Body); err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return payload, file, nil
+}
+
+func asFiberError(err error, status int) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr
+	}
+	return fiber.NewError(status, err.Error())
+}
+
+// lintReport extracts the report of a template rejected by the linter.
+func lintReport(err error) (*dto.LintReport, bool) {
+	var lintErr *service.LintError
+	if errors.As(err, &lintErr) {
+		return lintErr.Report, true
+	}
+	return nil, false
+}
+
+// serviceStatus maps the service's sentinel errors to the HTTP status they stand for.
+func serviceStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, service.ErrInfected):
+		return fiber.StatusUnprocessableEntity, true
+	case errors.Is(err, service.ErrNotScanned):
+		return fiber.StatusConflict, true
+	case errors.Is(err, service.ErrScanFailure):
+		return fiber.StatusServiceUnavailable, true
+	case errors.Is(err, service.ErrRender):
+		return fiber.StatusUnprocessableEntity, true
+	case errors.Is(err, service.ErrInvalidJob):
+		return fiber.StatusBadRequest, true
+	case errors.Is(err, service.ErrInclude):
+		return fiber.StatusUnprocessableEntity, true
+	case errors.Is(err, service.ErrLayout):
+		return fiber.StatusUnprocessableEntity, true
+	case errors.Is(err, service.ErrLocale):
+		return fiber.StatusBadRequest, true
+	case errors.Is(err, service.ErrAlias):
+		return fiber.StatusBadRequest, true
+	case errors.Is(err, service.ErrSlugTaken):
+		return fiber.StatusConflict, true
+	case errors.Is(err, service.ErrQuery):
+		return fiber.StatusBadRequest, true
+	case errors.Is(err, service.ErrNotPublished):
+		return fiber.StatusNotFound, true
+	case errors.Is(err, service.ErrTransition):
+		return fiber.StatusConflict, true
+	case errors.Is(err, service.ErrNotDraft):
+		return fiber.StatusConflict, true
+	case errors.Is(err, service.ErrForbidden):
+		return fiber.StatusForbidden, true
+	case errors.Is(err, service.ErrNotFound):
+		return fiber.StatusNotFound, true
+	default:
+		return 0, false
+	}
+}
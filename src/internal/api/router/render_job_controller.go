@@ -0,0 +1,55 @@
+package router
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/service"
+	"github.com/gofiber/fiber/v3"
+)
+
+type RenderJobController interface {
+	PostRenderJob(c fiber.Ctx) error
+	GetRenderJob(c fiber.Ctx) error
+}
+
+type renderJobController struct {
+	service service.RenderJobService
+}
+
+func NewRenderJobController(service service.RenderJobService) RenderJobController {
+	return &renderJobController{service: service}
+}
+
+func (r *renderJobController) PostRenderJob(c fiber.Ctx) error {
+	var payload dto.RenderJobRequest
+	if err := json.Unmarshal(c.Body(), &payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON payload: "+err.Error())
+	}
+
+	result, err := r.service.SubmitRender(requestContext(c), &payload)
+	if err != nil {
+		if status, ok := serviceStatus(err); ok {
+			return fiber.NewError(status, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	c.Set(fiber.HeaderLocation, strings.TrimSuffix(c.Path(), "/v1")+"/"+result.ID+"/v1")
+	return c.Status(fiber.StatusAccepted).JSON(result)
+}
+
+func (r *renderJobController) GetRenderJob(c fiber.Ctx) error {
+	id := c.Params("ID")
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "ID parameter is required")
+	}
+
+	result, err := r.service.FindRender(requestContext(c), id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Render job not found: "+err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
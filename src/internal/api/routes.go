@@ -3,43 +3,102 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"time"
 
 	AWS "github.com/antoniofrisenda/template-service/src/clients/aws"
+	"github.com/antoniofrisenda/template-service/src/clients/cache"
 	MONGO "github.com/antoniofrisenda/template-service/src/clients/mongo"
+	"github.com/antoniofrisenda/template-service/src/clients/ocr"
+	"github.com/antoniofrisenda/template-service/src/clients/scanner"
+	"github.com/antoniofrisenda/template-service/src/clients/webhook"
+	"github.com/antoniofrisenda/template-service/src/internal/api/middleware"
 	"github.com/antoniofrisenda/template-service/src/internal/api/router"
 	"github.com/antoniofrisenda/template-service/src/internal/assets/helpers"
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
 	"github.com/antoniofrisenda/template-service/src/internal/config"
+	"github.com/antoniofrisenda/template-service/src/internal/lint"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
+	"github.com/antoniofrisenda/template-service/src/internal/metrics"
+	"github.com/antoniofrisenda/template-service/src/internal/render"
 	"github.com/antoniofrisenda/template-service/src/internal/repository"
 	"github.com/antoniofrisenda/template-service/src/internal/service"
+	"github.com/antoniofrisenda/template-service/src/internal/tracing"
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/log"
 	"github.com/gofiber/fiber/v3/middleware/recover"
 	"github.com/gofiber/fiber/v3/middleware/requestid"
+	"github.com/unidoc/unipdf/v3/common/license"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+var logger = logging.For("api")
+
+// multipartOverhead covers the boundaries, part headers and form fields sent alongside an
+// uploaded file.
+const multipartOverhead = 1 << 20
+
+// bodyLimit admits the largest configured upload, so that oversized files reach the
+// per-type check and fail with 413 rather than being cut off by Fiber's 4 MiB default.
+func bodyLimit(cfg config.UploadConfig) int {
+	var largest int64
+	for _, size := range cfg.MaxSizes {
+		largest = max(largest, size)
+	}
+	return int(largest) + multipartOverhead
+}
+
 func Init(cfg *config.Config) (*fiber.App, error) {
 	app := fiber.New(fiber.Config{
 		JSONEncoder: json.Marshal,
 		JSONDecoder: json.Unmarshal,
+
+		BodyLimit: bodyLimit(cfg.Upload),
+
+		PassLocalsToContext: true,
 	})
 
 	app.Use(recover.New(recover.Config{
 		EnableStackTrace: true,
 	}))
 
-	app.Use(cfg.Logger.NewFiberLogger())
-
 	app.Use(requestid.New())
 
-	log.Info("Init app...")
+	app.Use(logging.NewAccessLog())
+
+	app.Use(metrics.NewMiddleware())
+
+	app.Use(tracing.NewMiddleware())
+
+	logger.Info("Init app...")
 
 	ctx := context.Background()
 
-	err := RegisterInternalRoute(ctx, cfg, app)
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing.Exporter, cfg.Tracing.ServiceName, cfg.Tracing.SampleRatio)
+	if err != nil {
+		return nil, err
+	}
+
+	app.Hooks().OnPostShutdown(func(error) error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+
+	if cfg.PDF.LicenseKey != "" {
+		if err := license.SetMeteredKey(cfg.PDF.LicenseKey); err != nil {
+			return nil, fmt.Errorf("invalid unipdf license key: %w", err)
+		}
+	}
+
+	checks, err := RegisterInternalRoute(ctx, cfg, app)
 	if err != nil {
 		return nil, err
 	}
 
+	RegisterHealthRoutes(app, cfg.App.HealthTimeout, checks...)
+
+	app.Get("/metrics", metrics.Handler())
+
 	app.Get("/", func(c fiber.Ctx) error {
 		return c.JSON(fiber.Map{
 			"service": "document-service",
@@ -48,17 +107,18 @@ func Init(cfg *config.Config) (*fiber.App, error) {
 		})
 	})
 
-	app.Get("/healthz", func(c fiber.Ctx) error {
-		return c.SendStatus(fiber.StatusOK)
-	})
-
-	log.Info("Init app OK!")
+	logger.Info("Init app OK!")
 
 	return app, nil
 }
 
-func RegisterInternalRoute(ctx context.Context, cfg *config.Config, app *fiber.App) error {
-	route := app.Group("/api/internal/templates")
+func RegisterInternalRoute(ctx context.Context, cfg *config.Config, app *fiber.App) ([]HealthCheck, error) {
+	verifier, err := auth.NewTokenVerifier(cfg.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	route := app.Group("/api/internal/templates", middleware.NewTenant(cfg.Tenant, verifier), middleware.NewPrincipal(cfg.Auth, verifier), middleware.NewLocale())
 
 	mongoClient, err := MONGO.NewMongoClient(
 		ctx,
@@ -69,36 +129,251 @@ func RegisterInternalRoute(ctx context.Context, cfg *config.Config, app *fiber.A
 		panic(err)
 	}
 
-	repo := repository.NewDocumentRepository(mongoClient.GetDB().Collection("templates"))
+	app.Hooks().OnPostShutdown(func(error) error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
+		defer cancel()
 
-	s3, err := AWS.NewS3ClientService(
-		ctx,
-		cfg.AWS.Region,
-		cfg.AWS.AccessKeyID,
-		cfg.AWS.SecretAccessKeyID,
-		cfg.AWS.URL,
-		cfg.AWS.S3BucketName,
-	)
+		if err := mongoClient.Disconnect(ctx); err != nil {
+			logger.Error("Mongo disconnect failed", "error", err)
+			return err
+		}
+
+		logger.Info("Mongo disconnected")
+		return nil
+	})
+
+	checks := []HealthCheck{
+		{Name: "mongo", Probe: mongoClient.Ping},
+	}
+
+	tenantCollections := make(map[string]*mongo.Collection, len(cfg.Tenant.Databases))
+	for tenant, db := range cfg.Tenant.Databases {
+		tenantCollections[tenant] = mongoClient.GetDatabase(db).Collection("templates")
+	}
+
+	documentCache, err := newCache(cfg.Cache)
 	if err != nil {
 		panic(err)
 	}
 
-	if err := s3.EnsureBucketExists(ctx); err != nil {
+	app.Hooks().OnPostShutdown(func(error) error {
+		if err := documentCache.Close(); err != nil {
+			logger.Error("Cache close failed", "error", err)
+			return err
+		}
+
+		logger.Info("Cache closed")
+		return nil
+	})
+
+	if cfg.Cache.Driver == "redis" {
+		checks = append(checks, HealthCheck{Name: "redis", Probe: documentCache.Ping})
+	}
+
+	documents := repository.NewCachedDocumentRepository(
+		repository.NewDocumentRepository(mongoClient.GetDB().Collection("templates"), tenantCollections),
+		documentCache,
+		cfg.Cache.TTL,
+	)
+
+	aliases := repository.NewAliasRepository(mongoClient.GetDB().Collection("aliases"))
+
+	if err := documents.EnsureIndexes(ctx); err != nil {
+		panic(err)
+	}
+
+	if err := aliases.EnsureIndexes(ctx); err != nil {
 		panic(err)
-	} else {
-		log.Info("S3 bucket OK!")
 	}
 
+	s3, err := newS3Client(ctx, cfg, cfg.AWS.S3BucketName)
+	if err != nil {
+		panic(err)
+	}
+
+	checks = append(checks, HealthCheck{Name: "s3", Probe: s3.EnsureBucketExists})
+
+	buckets := make(map[string]AWS.S3Client, len(cfg.Tenant.Buckets))
+	for tenant, bucket := range cfg.Tenant.Buckets {
+		buckets[tenant], err = newS3Client(ctx, cfg, bucket)
+		if err != nil {
+			panic(err)
+		}
+
+		checks = append(checks, HealthCheck{Name: "s3:" + tenant, Probe: buckets[tenant].EnsureBucketExists})
+	}
+
+	logger.Info("S3 bucket OK!")
+
 	mapper := helpers.NewDocumentMapper()
 
-	service := service.NewDocumentService(repo, mapper, s3)
+	fileScanner, err := newScanner(cfg.Scanner)
+	if err != nil {
+		panic(err)
+	}
+
+	recognizer, err := newOCR(cfg.OCR)
+	if err != nil {
+		panic(err)
+	}
+
+	sanitizer, err := render.NewSanitizer(cfg.HTML.Policy, cfg.HTML.AllowedTags, cfg.HTML.AllowedAttributes)
+	if err != nil {
+		panic(err)
+	}
+
+	linter, err := lint.NewLinter(cfg.Lint.Severities, cfg.Lint.BlockOn, cfg.Lint.Strict)
+	if err != nil {
+		panic(err)
+	}
+
+	limits := service.ExtractLimits{
+		Workers:  int(cfg.Extract.Workers),
+		MaxPages: int(cfg.Extract.MaxPages),
+		MaxBytes: cfg.Extract.MaxBytes,
+		Timeout:  cfg.Extract.Timeout,
+	}
+
+	documentService := service.NewTracedDocumentService(
+		service.NewDocumentService(documents, aliases, mapper, s3, buckets, fileScanner, recognizer, render.NewRenderer(nil), sanitizer, render.NewComposer(), linter, documentCache, cfg.Cache.TTL, limits, int(cfg.Render.MaxIncludeDepth), int(cfg.Render.MaxIncludeBytes)),
+	)
+
+	if cfg.Extract.ReextractOnStart {
+		jobCtx, cancel := context.WithCancel(context.Background())
+		app.Hooks().OnPreShutdown(func() error {
+			cancel()
+			return nil
+		})
+
+		go func() {
+			if _, err := documentService.ReextractVariables(jobCtx); err != nil && jobCtx.Err() == nil {
+				logger.Error("Variable re-extraction failed", "error", err)
+			}
+		}()
+	}
+
+	var notifier webhook.Notifier
+	if cfg.Jobs.WebhookSecret != "" {
+		notifier = webhook.NewHTTPNotifier(cfg.Jobs.WebhookSecret, cfg.Jobs.WebhookTimeout, int(cfg.Jobs.WebhookAttempts), cfg.Jobs.WebhookBackoff, cfg.Jobs.WebhookAllowedNetworks)
+	}
+
+	jobs := service.NewRenderJobService(
+		repository.NewRenderJobRepository(mongoClient.GetDB().Collection("render_jobs")),
+		documents,
+		aliases,
+		documentService,
+		s3,
+		buckets,
+		notifier,
+		service.JobOptions{
+			Workers:        int(cfg.Jobs.Workers),
+			PollInterval:   cfg.Jobs.PollInterval,
+			Lease:          cfg.Jobs.Lease,
+			MaxAttempts:    int(cfg.Jobs.MaxAttempts),
+			ResultLifetime: cfg.Jobs.ResultLifetime,
+		},
+	)
 
-	controller := router.NewDocumentController(service)
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		jobs.Run(workersCtx)
+	}()
 
+	// Workers finish the jobs in progress before Mongo disconnects, within the shutdown timeout.
+	app.Hooks().OnPreShutdown(func() error {
+		stopWorkers()
+		select {
+		case <-workersDone:
+		case <-time.After(cfg.App.ShutdownTimeout):
+			logger.Warn("Render workers did not stop in time")
+		}
+		return nil
+	})
+
+	controller := router.NewDocumentController(documentService, cfg.Upload)
+	jobController := router.NewRenderJobController(jobs)
+	aliasController := router.NewAliasController(service.NewAliasService(aliases, documents))
+
+	route.Get("/v1", controller.ListTemplates)
+	route.Get("/search/v1", controller.SearchTemplates)
 	route.Get("/url/:ID/v1", controller.GetPresigned)
 	route.Get("/variables/latest/:ID/v1", controller.GetLatestVariables)
 	route.Get("/:DocumentType/:SourceType/:ID/v1", controller.GetTemplate)
+	route.Post("/render/:ID/v1", controller.PostRender)
+	route.Post("/render/:ID/batch/v1", controller.PostRenderBatch)
+	route.Post("/compose/v1", controller.PostCompose)
+	route.Post("/renders/v1", jobController.PostRenderJob)
+	route.Get("/renders/:ID/v1", jobController.GetRenderJob)
+	route.Get("/aliases/:Alias/v1", aliasController.GetAlias)
+	route.Put("/aliases/:Alias/v1", aliasController.PutAlias)
+	route.Delete("/aliases/:Alias/v1", aliasController.DeleteAlias)
+	route.Post("/lint/:DocumentType/:SourceType/v1", controller.PostLint)
+	route.Post("/:ID/transitions/v1", controller.PostTransition)
 	route.Post("/:DocumentType/:SourceType/v1", controller.PostTemplate)
+	route.Put("/:DocumentType/:SourceType/:ID/v1", controller.PutTemplate)
+	route.Put("/:DocumentType/:SourceType/:ID/locales/:Locale/v1", controller.PutLocale)
+	route.Delete("/:ID/v1", controller.DeleteTemplate)
+	route.Delete("/:ID/locales/:Locale/v1", controller.DeleteLocale)
+
+	return checks, nil
+}
 
-	return nil
+func newS3Client(ctx context.Context, cfg *config.Config, bucket string) (AWS.S3Client, error) {
+	s3, err := AWS.NewS3ClientService(
+		ctx,
+		cfg.AWS.Region,
+		cfg.AWS.AccessKeyID,
+		cfg.AWS.SecretAccessKeyID,
+		cfg.AWS.URL,
+		bucket,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s3.EnsureBucketExists(ctx); err != nil {
+		return nil, err
+	}
+
+	return metrics.NewS3Client(s3), nil
+}
+
+func newScanner(cfg config.ScannerConfig) (scanner.Scanner, error) {
+	switch cfg.Driver {
+	case "noop":
+		return scanner.NewNoopScanner(), nil
+	case "clamav":
+		return scanner.NewClamAVScanner(cfg.ClamAVAddress, cfg.ClamAVTimeout), nil
+	default:
+		return nil, fmt.Errorf("unsupported scanner: %s (must be noop or clamav)", cfg.Driver)
+	}
+}
+
+func newOCR(cfg config.OCRConfig) (ocr.OCR, error) {
+	switch cfg.Driver {
+	case "none":
+		return ocr.NewNoopOCR(), nil
+	case "tesseract":
+		return ocr.NewTesseractOCR(cfg.TesseractPath, cfg.Languages, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unsupported ocr: %s (must be none or tesseract)", cfg.Driver)
+	}
+}
+
+func newCache(cfg config.CacheConfig) (cache.Cache, error) {
+	switch cfg.Driver {
+	case "none":
+		return cache.NewNoopCache(), nil
+	case "memory":
+		return cache.NewLRUCache(int(cfg.MaxEntries), cfg.MaxBytes), nil
+	case "redis":
+		if cfg.RedisURL == "" {
+			return nil, fmt.Errorf("REDIS_URL is required when CACHE_DRIVER is redis")
+		}
+		return cache.NewRedisCache(cfg.RedisURL, cfg.RedisPrefix)
+	default:
+		return nil, fmt.Errorf("unsupported cache: %s (must be none, memory or redis)", cfg.Driver)
+	}
 }
@@ -1,31 +1,286 @@
 package dto
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
 )
 
 type Document struct {
-	ID            string             `json:"id"`
-	Name          string             `json:"name"`
-	Summary       string             `json:"summary"`
-	Type          model.DocumentType `json:"type"`
-	Source        model.SourceType   `json:"source"`
-	ContentType   model.ContentType  `json:"contentType"`
-	Base64Encoded bool               `json:"base64Encoded"`
-	Body          string             `json:"body"`
+	ID            string               `json:"id"`
+	Name          string               `json:"name"`
+	Slug          string               `json:"slug,omitempty"`
+	Summary       string               `json:"summary"`
+	Tags          []string             `json:"tags,omitempty"`
+	Category      string               `json:"category,omitempty"`
+	Metadata      map[string]string    `json:"metadata,omitempty"`
+	Type          model.DocumentType   `json:"type"`
+	Source        model.SourceType     `json:"source"`
+	ContentType   model.ContentType    `json:"contentType"`
+	ScanStatus    model.ScanStatus     `json:"scanStatus,omitempty"`
+	Base64Encoded bool                 `json:"base64Encoded"`
+	Layout        string               `json:"layout,omitempty"`
+	Locale        string               `json:"locale,omitempty"`
+	Locales       []string             `json:"locales,omitempty"`
+	State         model.LifecycleState `json:"state"`
+	ApprovedBy    string               `json:"approvedBy,omitempty"`
+	ApprovedAt    *time.Time           `json:"approvedAt,omitempty"`
+	Transitions   []Transition         `json:"transitions,omitempty"`
+	Body          string               `json:"body"`
+	Variables     []DocumentVariable   `json:"variables,omitempty"`
+	Lint          *LintReport          `json:"lint,omitempty"`
+	Diagnostics   *Diagnostics         `json:"diagnostics,omitempty"`
+}
+
+// DocumentSummary describes a document without its body, as listed by searches.
+type DocumentSummary struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Slug        string               `json:"slug,omitempty"`
+	Summary     string               `json:"summary"`
+	Tags        []string             `json:"tags,omitempty"`
+	Category    string               `json:"category,omitempty"`
+	Metadata    map[string]string    `json:"metadata,omitempty"`
+	Type        model.DocumentType   `json:"type"`
+	Source      model.SourceType     `json:"source"`
+	ContentType model.ContentType    `json:"contentType"`
+	ScanStatus  model.ScanStatus     `json:"scanStatus,omitempty"`
+	Layout      string               `json:"layout,omitempty"`
+	State       model.LifecycleState `json:"state"`
+}
+
+// DocumentQuery filters the documents listed: every tag, the category and every metadata
+// pair must match. Cursor continues a previous page.
+type DocumentQuery struct {
+	Tags        []string
+	Category    string
+	Metadata    map[string]string
+	Type        model.DocumentType
+	Source      model.SourceType
+	ContentType model.ContentType
+	State       model.LifecycleState
+	Limit       int
+	Cursor      string
+}
+
+// TransitionRequest moves a document to another lifecycle state.
+type TransitionRequest struct {
+	State   model.LifecycleState `json:"state"`
+	Comment string               `json:"comment,omitempty"`
+}
+
+type Transition struct {
+	From    model.LifecycleState `json:"from"`
+	To      model.LifecycleState `json:"to"`
+	By      string               `json:"by"`
+	Comment string               `json:"comment,omitempty"`
+	At      time.Time            `json:"at"`
+}
+
+// DocumentPage holds a page of documents; Next is the cursor of the following page, empty on
+// the last one.
+type DocumentPage struct {
+	Documents []DocumentSummary `json:"documents"`
+	Next      string            `json:"next,omitempty"`
+}
+
+// SearchResults lists the documents matching a full-text search, most relevant first.
+type SearchResults struct {
+	Query   string      `json:"query"`
+	Results []SearchHit `json:"results"`
+}
+
+// SearchHit is a document matching a full-text search with its text score and the snippets
+// of its fields where the search terms occur.
+type SearchHit struct {
+	Document   DocumentSummary `json:"document"`
+	Score      float64         `json:"score"`
+	Highlights []Highlight     `json:"highlights,omitempty"`
+}
+
+// Highlight holds snippets of a field, name, summary or body, with the search terms wrapped
+// in <mark> tags and the rest HTML escaped.
+type Highlight struct {
+	Field    string   `json:"field"`
+	Snippets []string `json:"snippets"`
+}
+
+// DocumentVariable merges a declared variable with the extracted one of the same name.
+type DocumentVariable struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Type        model.VariableType `json:"type,omitempty"`
+	Declared    bool               `json:"declared"`
+	Extracted   bool               `json:"extracted"`
 }
 
 type InsertDocument struct {
 	Name        string             `json:"name"`
+	Slug        string             `json:"slug,omitempty"`
 	Summary     string             `json:"summary"`
+	Tags        []string           `json:"tags,omitempty"`
+	Category    string             `json:"category,omitempty"`
+	Metadata    map[string]string  `json:"metadata,omitempty"`
 	Type        model.DocumentType `json:"type"`
 	Source      model.SourceType   `json:"source"`
 	ContentType model.ContentType  `json:"contentType"`
+	Layout      string             `json:"layout,omitempty"`
 	Body        *InsertBody        `json:"body"`
 }
 
 type InsertBody struct {
-	URL       *string  `json:"url,omitempty"`
-	Text      *string  `json:"text,omitempty"`
-	Variables []string `json:"variables,omitempty"`
+	URL       *string               `json:"url,omitempty"`
+	Text      *string               `json:"text,omitempty"`
+	Variables []VariableDeclaration `json:"variables,omitempty"`
+}
+
+// VariableDeclaration is either a bare name, "name", or an object with its metadata.
+type VariableDeclaration struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Type        model.VariableType `json:"type,omitempty"`
+}
+
+func (v *VariableDeclaration) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*v = VariableDeclaration{Name: name}
+		return nil
+	}
+
+	type declaration VariableDeclaration
+	return json.Unmarshal(data, (*declaration)(v))
+}
+
+type ExtractedVariables struct {
+	Variables   []string     `json:"variables"`
+	Occurrences []Variable   `json:"occurrences,omitempty"`
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+}
+
+// Diagnostics explains why an extraction is partial: limits that were hit and pages that
+// could not be extracted.
+type Diagnostics struct {
+	Partial        bool        `json:"partial"`
+	Pages          int         `json:"pages,omitempty"`
+	ExtractedPages int         `json:"extractedPages,omitempty"`
+	Reasons        []string    `json:"reasons,omitempty"`
+	Errors         []PageError `json:"errors,omitempty"`
+}
+
+type PageError struct {
+	Page  int    `json:"page"`
+	Error string `json:"error"`
+}
+
+type Variable struct {
+	Name      string     `json:"name"`
+	Count     int        `json:"count"`
+	Positions []Position `json:"positions"`
+}
+
+// Position locates a placeholder: Page for PDF files, Line and Column for text.
+type Position struct {
+	Page   int `json:"page,omitempty"`
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+type LintReport struct {
+	Blocking    bool         `json:"blocking"`
+	Issues      []LintIssue  `json:"issues"`
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+}
+
+type LintIssue struct {
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	Message  string    `json:"message"`
+	Variable string    `json:"variable,omitempty"`
+	Position *Position `json:"position,omitempty"`
+}
+
+type RenderRequest struct {
+	Variables map[string]any `json:"variables"`
+}
+
+type RenderedDocument struct {
+	ID          string            `json:"id"`
+	ContentType model.ContentType `json:"contentType"`
+	Locale      string            `json:"locale,omitempty"`
+	Body        string            `json:"body"`
+}
+
+// BatchResult summarises a batch render. URL is set when the archive was stored in S3.
+type BatchResult struct {
+	Rows     int        `json:"rows"`
+	Rendered int        `json:"rendered"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors"`
+	URL      string     `json:"url,omitempty"`
+}
+
+// RowError is the failure of a batch row; Row counts from 1.
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type RenderJobRequest struct {
+	DocumentID  string         `json:"documentId"`
+	Variables   map[string]any `json:"variables"`
+	CallbackURL string         `json:"callbackUrl,omitempty"`
+}
+
+// RenderJob is the status of a background render. ResultURL is a presigned link to the
+// rendered document, set once the job succeeded.
+type RenderJob struct {
+	ID          string            `json:"id"`
+	DocumentID  string            `json:"documentId"`
+	Locales     []string          `json:"locales,omitempty"`
+	Status      model.JobStatus   `json:"status"`
+	Attempts    int               `json:"attempts"`
+	Error       string            `json:"error,omitempty"`
+	ContentType model.ContentType `json:"contentType,omitempty"`
+	ResultURL   string            `json:"resultUrl,omitempty"`
+	Webhook     *WebhookStatus    `json:"webhook,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	StartedAt   *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time        `json:"finishedAt,omitempty"`
+}
+
+type WebhookStatus struct {
+	URL         string     `json:"url"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// ComposeRequest lists the documents merged into a single PDF, in order.
+type ComposeRequest struct {
+	Documents       []ComposePart `json:"documents"`
+	Bookmarks       bool          `json:"bookmarks"`
+	PageNumbers     bool          `json:"pageNumbers"`
+	TableOfContents bool          `json:"tableOfContents"`
+}
+
+// ComposePart is a document to merge. Title defaults to the document name; Variables are
+// used to render TEMPLATE documents.
+type ComposePart struct {
+	ID        string         `json:"id"`
+	Title     string         `json:"title,omitempty"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// Alias is a movable name for a template, e.g. invoice@prod.
+type Alias struct {
+	Name       string    `json:"name"`
+	DocumentID string    `json:"documentId"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// AliasRequest points an alias at the template whose ID or slug is Target.
+type AliasRequest struct {
+	Target string `json:"target"`
 }
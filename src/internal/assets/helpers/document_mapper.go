@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
 	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
@@ -10,6 +11,7 @@ import (
 type DocumentMapper interface {
 	ToDTO(m *model.Document) (*dto.Document, error)
 	ToModel(m *dto.InsertDocument) (*model.Document, error)
+	ToSummary(m *model.Document) dto.DocumentSummary
 }
 
 type documentMapper struct{}
@@ -18,6 +20,24 @@ func NewDocumentMapper() DocumentMapper {
 	return &documentMapper{}
 }
 
+func (dm *documentMapper) ToSummary(m *model.Document) dto.DocumentSummary {
+	return dto.DocumentSummary{
+		ID:          m.ID.Hex(),
+		Name:        m.Name,
+		Slug:        m.Slug,
+		Summary:     m.Summary,
+		Tags:        m.Tags,
+		Category:    m.Category,
+		Metadata:    m.Metadata,
+		Type:        m.Type,
+		Source:      m.Source,
+		ContentType: m.ContentType,
+		ScanStatus:  m.Scan(),
+		Layout:      m.Layout,
+		State:       m.State.Current(),
+	}
+}
+
 func (dm *documentMapper) ToDTO(m *model.Document) (*dto.Document, error) {
 	var (
 		base64Encoded bool
@@ -65,15 +85,93 @@ func (dm *documentMapper) ToDTO(m *model.Document) (*dto.Document, error) {
 	return &dto.Document{
 		ID:            m.ID.Hex(),
 		Name:          m.Name,
+		Slug:          m.Slug,
 		Summary:       m.Summary,
+		Tags:          m.Tags,
+		Category:      m.Category,
+		Metadata:      m.Metadata,
 		Type:          m.Type,
 		Source:        m.Source,
 		ContentType:   m.ContentType,
+		ScanStatus:    m.Scan(),
 		Base64Encoded: base64Encoded,
+		Layout:        m.Layout,
+		Locale:        m.Body.Locale,
+		State:         m.State.Current(),
+		ApprovedBy:    m.ApprovedBy,
+		ApprovedAt:    m.ApprovedAt,
+		Transitions:   transitions(m.Transitions),
+		Locales:       locales(m.Locales),
 		Body:          body,
+		Variables:     mergeVariables(m.Body),
 	}, nil
 }
 
+// mergeVariables lists extracted variables in order, enriched with their declaration, then
+// the declared variables that were not extracted.
+func mergeVariables(body *model.DocumentBody) []dto.DocumentVariable {
+	if body == nil || (len(body.Variables) == 0 && len(body.Declared) == 0) {
+		return nil
+	}
+
+	declared := make(map[string]model.VariableDeclaration, len(body.Declared))
+	for _, d := range body.Declared {
+		declared[d.Name] = d
+	}
+
+	merged := make([]dto.DocumentVariable, 0, len(body.Variables)+len(body.Declared))
+	extracted := make(map[string]bool, len(body.Variables))
+	for _, name := range body.Variables {
+		d, ok := declared[name]
+		merged = append(merged, dto.DocumentVariable{
+			Name:        name,
+			Description: d.Description,
+			Type:        d.Type,
+			Declared:    ok,
+			Extracted:   true,
+		})
+		extracted[name] = true
+	}
+
+	for _, d := range body.Declared {
+		if !extracted[d.Name] {
+			merged = append(merged, dto.DocumentVariable{
+				Name:        d.Name,
+				Description: d.Description,
+				Type:        d.Type,
+				Declared:    true,
+			})
+		}
+	}
+
+	return merged
+}
+
 func (dm *documentMapper) ToModel(d *dto.InsertDocument) (*model.Document, error) {
 	return Register(d), nil
 }
+
+// locales lists the locales of the variants of a document, sorted.
+func locales(variants map[string]*model.DocumentBody) []string {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	tags := make([]string, 0, len(variants))
+	for tag := range variants {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+func transitions(history []model.Transition) []dto.Transition {
+	if len(history) == 0 {
+		return nil
+	}
+	result := make([]dto.Transition, len(history))
+	for i, t := range history {
+		result[i] = dto.Transition{From: t.From, To: t.To, By: t.By, Comment: t.Comment, At: t.At}
+	}
+	return result
+}
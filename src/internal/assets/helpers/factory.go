@@ -1,11 +1,39 @@
 package helpers
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
 	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
 )
 
 func Register(dto *dto.InsertDocument) *model.Document {
+	doc := register(dto)
+	if doc != nil {
+		doc.Slug = dto.Slug
+		doc.Tags = tags(dto.Tags)
+		doc.Category = strings.TrimSpace(dto.Category)
+		if len(dto.Metadata) > 0 {
+			doc.Metadata = dto.Metadata
+		}
+	}
+	return doc
+}
+
+// tags trims and lowercases tags, dropping duplicates.
+func tags(values []string) []string {
+	var result []string
+	for _, v := range values {
+		tag := strings.ToLower(strings.TrimSpace(v))
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func register(dto *dto.InsertDocument) *model.Document {
 	if dto == nil {
 		return nil
 	}
@@ -29,11 +57,25 @@ func Register(dto *dto.InsertDocument) *model.Document {
 			if dto.Body == nil || dto.Body.Text == nil {
 				return nil
 			}
-			return model.NewTemplateTextDocument(dto.Name, dto.Summary, contentType, *dto.Body.Text, dto.Body.Variables)
+			doc := model.NewTemplateTextDocument(dto.Name, dto.Summary, contentType, *dto.Body.Text, declarations(dto.Body))
+			doc.Layout = strings.TrimSpace(dto.Layout)
+			return doc
 		case "FILE":
-			return model.NewTemplateFileDocument(dto.Name, dto.Summary, contentType, "", dto.Body.Variables)
+			return model.NewTemplateFileDocument(dto.Name, dto.Summary, contentType, "", declarations(dto.Body))
 		}
 	}
 
 	return nil
 }
+
+func declarations(body *dto.InsertBody) []model.VariableDeclaration {
+	if body == nil || len(body.Variables) == 0 {
+		return nil
+	}
+
+	declared := make([]model.VariableDeclaration, len(body.Variables))
+	for i, v := range body.Variables {
+		declared[i] = model.VariableDeclaration{Name: v.Name, Description: v.Description, Type: v.Type}
+	}
+	return declared
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Alias is a movable name, such as invoice@prod, for a template. Re-pointing it switches
+// every consumer resolving the alias to the new template.
+type Alias struct {
+	Tenant     string             `bson:"tenant"`
+	Name       string             `bson:"name"`
+	DocumentID primitive.ObjectID `bson:"documentId"`
+	UpdatedAt  time.Time          `bson:"updatedAt"`
+}
+
+func NewAlias(name string, documentID primitive.ObjectID) *Alias {
+	return &Alias{
+		Name:       name,
+		DocumentID: documentID,
+		UpdatedAt:  time.Now().UTC(),
+	}
+}
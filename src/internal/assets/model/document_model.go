@@ -1,21 +1,106 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Document struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty"`
-	Name        string             `bson:"name"`
-	Summary     string             `bson:"summary"`
-	Type        DocumentType       `bson:"type"`
-	Source      SourceType         `bson:"source"`
-	ContentType ContentType        `bson:"contentType"`
-	Body        *DocumentBody      `bson:"body"`
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	Tenant   string             `bson:"tenant"`
+	Name     string             `bson:"name"`
+	Slug     string             `bson:"slug,omitempty"`
+	Summary  string             `bson:"summary"`
+	Tags     []string           `bson:"tags,omitempty"`
+	Category string             `bson:"category,omitempty"`
+
+	// Metadata holds free-form key/value pairs, e.g. product, channel or owner.
+	Metadata map[string]string `bson:"metadata,omitempty"`
+
+	Type        DocumentType  `bson:"type"`
+	Source      SourceType    `bson:"source"`
+	ContentType ContentType   `bson:"contentType"`
+	ScanStatus  ScanStatus    `bson:"scanStatus,omitempty"`
+	Body        *DocumentBody `bson:"body"`
+
+	// Layout references, by ID or name, the TEXT template this one extends, if any.
+	Layout string `bson:"layout,omitempty"`
+
+	// Locales holds the localized variants of Body, keyed by BCP 47 language tag. They share
+	// the type, source and content type of the document.
+	Locales map[string]*DocumentBody `bson:"locales,omitempty"`
+
+	// State is the lifecycle state; consumers only see PUBLISHED documents. ApprovedBy and
+	// ApprovedAt record who published the document, and Transitions every change of state.
+	State       LifecycleState `bson:"state,omitempty"`
+	ApprovedBy  string         `bson:"approvedBy,omitempty"`
+	ApprovedAt  *time.Time     `bson:"approvedAt,omitempty"`
+	Transitions []Transition   `bson:"transitions,omitempty"`
+}
+
+// Scan returns the scan status of the document, FILE documents stored before scanning was
+// introduced being PENDING_SCAN, as their content was never scanned.
+func (m *Document) Scan() ScanStatus {
+	if m.ScanStatus == "" && m.Source == FILE {
+		return PENDING_SCAN
+	}
+	return m.ScanStatus
+}
+
+// Transition records a change of the lifecycle state of a document.
+type Transition struct {
+	From    LifecycleState `bson:"from"`
+	To      LifecycleState `bson:"to"`
+	By      string         `bson:"by"`
+	Comment string         `bson:"comment,omitempty"`
+	At      time.Time      `bson:"at"`
 }
 
 type DocumentBody struct {
 	URL       *string  `bson:"url,omitempty"`
 	Text      *string  `bson:"text,omitempty"`
 	Variables []string `bson:"variables,omitempty"`
+
+	// Declared holds the variables declared by the client, with their metadata. They are
+	// reconciled with the extracted Variables rather than replaced by them.
+	Declared []VariableDeclaration `bson:"declared,omitempty"`
+
+	// ParserVersion records the extractor that produced Variables; zero means never extracted.
+	ParserVersion int `bson:"parserVersion,omitempty"`
+
+	// Content is the text extracted from a FILE template, kept for full-text search.
+	Content string `bson:"content,omitempty"`
+
+	// Locale is the language tag of a localized variant; empty for the default body.
+	Locale string `bson:"locale,omitempty"`
+}
+
+type VariableDeclaration struct {
+	Name        string       `bson:"name"`
+	Description string       `bson:"description,omitempty"`
+	Type        VariableType `bson:"type,omitempty"`
+}
+
+// DocumentFilter selects documents of a tenant: every tag, the category and every metadata
+// pair must match, as well as the type, source, content type and state when set. Results start
+// after the document After and hold at most Limit documents.
+type DocumentFilter struct {
+	Tags        []string
+	Category    string
+	Metadata    map[string]string
+	Type        DocumentType
+	Source      SourceType
+	ContentType ContentType
+	State       LifecycleState
+	After       primitive.ObjectID
+	Limit       int
+}
+
+// SearchHit is a document matching a full-text search, with its relevance score.
+type SearchHit struct {
+	Document `bson:",inline"`
+	Score    float64 `bson:"score"`
 }
 
 func NewStaticFileDocument(name string, summary string, contentType ContentType, url string) *Document {
@@ -25,6 +110,7 @@ func NewStaticFileDocument(name string, summary string, contentType ContentType,
 		Type:        STATIC,
 		Source:      FILE,
 		ContentType: contentType,
+		ScanStatus:  PENDING_SCAN,
 		Body: &DocumentBody{
 			URL: &url,
 		},
@@ -44,21 +130,22 @@ func NewStaticTextDocument(name, summary string, contentType ContentType, text s
 	}
 }
 
-func NewTemplateFileDocument(name, summary string, contentType ContentType, url string, variables []string) *Document {
+func NewTemplateFileDocument(name, summary string, contentType ContentType, url string, declared []VariableDeclaration) *Document {
 	return &Document{
 		Name:        name,
 		Summary:     summary,
 		Type:        TEMPLATE,
 		Source:      FILE,
 		ContentType: contentType,
+		ScanStatus:  PENDING_SCAN,
 		Body: &DocumentBody{
-			URL:       &url,
-			Variables: variables,
+			URL:      &url,
+			Declared: declared,
 		},
 	}
 }
 
-func NewTemplateTextDocument(name, summary string, contentType ContentType, text string, variables []string) *Document {
+func NewTemplateTextDocument(name, summary string, contentType ContentType, text string, declared []VariableDeclaration) *Document {
 	return &Document{
 		Name:        name,
 		Summary:     summary,
@@ -66,8 +153,20 @@ func NewTemplateTextDocument(name, summary string, contentType ContentType, text
 		Source:      TEXT,
 		ContentType: contentType,
 		Body: &DocumentBody{
-			Text:      &text,
-			Variables: variables,
+			Text:     &text,
+			Declared: declared,
 		},
 	}
 }
+
+// Localize replaces Body with the first variant found along chain, returning its locale, or
+// keeps the default body and returns an empty string when there is none.
+func (d *Document) Localize(chain []string) string {
+	for _, tag := range chain {
+		if variant, ok := d.Locales[tag]; ok && variant != nil {
+			d.Body = variant
+			return tag
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package model
+
+import "testing"
+
+func TestDocumentScan(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		doc          Document
+		status       ScanStatus
+		downloadable bool
+	}{
+		{"text", Document{Source: TEXT}, "", true},
+		{"clean file", Document{Source: FILE, ScanStatus: CLEAN}, CLEAN, true},
+		{"pending file", Document{Source: FILE, ScanStatus: PENDING_SCAN}, PENDING_SCAN, false},
+		{"infected file", Document{Source: FILE, ScanStatus: INFECTED}, INFECTED, false},
+		{"file stored before scanning", Document{Source: FILE}, PENDING_SCAN, false},
+	} {
+		status := tc.doc.Scan()
+		if status != tc.status || status.IsDownloadable() != tc.downloadable {
+			t.Errorf("%s: status = %q, downloadable = %v", tc.name, status, status.IsDownloadable())
+		}
+	}
+}
+
+func TestNewFileDocumentsPendScan(t *testing.T) {
+	doc := NewStaticFileDocument("logo", "", IMAGE, "logo.png")
+	if doc.ScanStatus != PENDING_SCAN || doc.Scan().IsDownloadable() {
+		t.Fatalf("new file document: status = %q", doc.ScanStatus)
+	}
+}
+
+func TestDocumentLocalize(t *testing.T) {
+	body := func(text string) *DocumentBody { return &DocumentBody{Text: &text} }
+	locales := map[string]*DocumentBody{"it": body("ciao"), "en-GB": body("hello, mate")}
+
+	for _, tc := range []struct {
+		name   string
+		chain  []string
+		locale string
+		text   string
+	}{
+		{"no locale requested", nil, "", "hello"},
+		{"exact variant", []string{"en-GB", "en"}, "en-GB", "hello, mate"},
+		{"parent variant", []string{"it-IT", "it"}, "it", "ciao"},
+		{"next preferred locale", []string{"fr-FR", "fr", "it"}, "it", "ciao"},
+		{"no variant", []string{"de-DE", "de"}, "", "hello"},
+		// The default body is written in English: requesting it finds no variant and keeps it.
+		{"default locale", []string{"en-US", "en"}, "", "hello"},
+	} {
+		doc := Document{Body: body("hello"), Locales: locales}
+		if locale := doc.Localize(tc.chain); locale != tc.locale || *doc.Body.Text != tc.text {
+			t.Errorf("%s: locale %q with %q, want %q with %q", tc.name, locale, *doc.Body.Text, tc.locale, tc.text)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "slices"
+
 type DocumentType string
 
 const (
@@ -34,3 +36,90 @@ const (
 func (e SourceType) IsValid() bool {
 	return e == FILE || e == TEXT
 }
+
+type VariableType string
+
+const (
+	STRING  VariableType = "STRING"
+	NUMBER  VariableType = "NUMBER"
+	BOOLEAN VariableType = "BOOLEAN"
+	DATE    VariableType = "DATE"
+)
+
+// IsValid accepts the empty type, used for untyped declarations.
+func (e VariableType) IsValid() bool {
+	return e == "" || e == STRING || e == NUMBER || e == BOOLEAN || e == DATE
+}
+
+// ScanStatus records the malware scan of a FILE document. Uploads are scanned before they
+// are stored and infected ones are rejected, so files are stored CLEAN.
+type ScanStatus string
+
+const (
+	PENDING_SCAN ScanStatus = "PENDING_SCAN"
+	CLEAN        ScanStatus = "CLEAN"
+	INFECTED     ScanStatus = "INFECTED"
+)
+
+func (e ScanStatus) IsValid() bool {
+	return e == PENDING_SCAN || e == CLEAN || e == INFECTED
+}
+
+// IsDownloadable reports whether content may be served. TEXT documents are not scanned and
+// carry no status.
+func (e ScanStatus) IsDownloadable() bool {
+	return e == CLEAN || e == ""
+}
+
+type JobStatus string
+
+const (
+	QUEUED    JobStatus = "QUEUED"
+	RUNNING   JobStatus = "RUNNING"
+	SUCCEEDED JobStatus = "SUCCEEDED"
+	FAILED    JobStatus = "FAILED"
+)
+
+func (e JobStatus) IsValid() bool {
+	return e == QUEUED || e == RUNNING || e == SUCCEEDED || e == FAILED
+}
+
+// IsFinal reports whether a job in this status will not run again.
+func (e JobStatus) IsFinal() bool {
+	return e == SUCCEEDED || e == FAILED
+}
+
+type LifecycleState string
+
+const (
+	DRAFT     LifecycleState = "DRAFT"
+	IN_REVIEW LifecycleState = "IN_REVIEW"
+	PUBLISHED LifecycleState = "PUBLISHED"
+	ARCHIVED  LifecycleState = "ARCHIVED"
+)
+
+// transitions lists the states each state can move to.
+var transitions = map[LifecycleState][]LifecycleState{
+	DRAFT:     {IN_REVIEW, ARCHIVED},
+	IN_REVIEW: {DRAFT, PUBLISHED},
+	PUBLISHED: {ARCHIVED},
+	ARCHIVED:  {DRAFT},
+}
+
+func (e LifecycleState) IsValid() bool {
+	return e == DRAFT || e == IN_REVIEW || e == PUBLISHED || e == ARCHIVED
+}
+
+// Current returns the state, documents stored before lifecycle states were introduced being
+// PUBLISHED, as they were live.
+func (e LifecycleState) Current() LifecycleState {
+	if e == "" {
+		return PUBLISHED
+	}
+	return e
+}
+
+// CanTransition reports whether a document in this state can move to state to.
+func (e LifecycleState) CanTransition(to LifecycleState) bool {
+	return slices.Contains(transitions[e.Current()], to)
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RenderJob is a render of a template run in the background by a worker. A worker locks the
+// job until LockedUntil; jobs whose lock expires, e.g. after a crash, are claimed again.
+type RenderJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Tenant      string             `bson:"tenant"`
+	DocumentID  primitive.ObjectID `bson:"documentId"`
+	Variables   map[string]any     `bson:"variables,omitempty"`
+	Locales     []string           `bson:"locales,omitempty"`
+	Preview     bool               `bson:"preview,omitempty"`
+	Status      JobStatus          `bson:"status"`
+	Attempts    int                `bson:"attempts"`
+	Error       string             `bson:"error,omitempty"`
+	ContentType ContentType        `bson:"contentType,omitempty"`
+	ResultURL   *string            `bson:"resultUrl,omitempty"`
+	Webhook     *Webhook           `bson:"webhook,omitempty"`
+	LockedUntil *time.Time         `bson:"lockedUntil,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt"`
+	StartedAt   *time.Time         `bson:"startedAt,omitempty"`
+	FinishedAt  *time.Time         `bson:"finishedAt,omitempty"`
+}
+
+// Webhook is the callback notified once a job is final, with the outcome of its delivery.
+type Webhook struct {
+	URL         string     `bson:"url"`
+	Attempts    int        `bson:"attempts,omitempty"`
+	Error       string     `bson:"error,omitempty"`
+	DeliveredAt *time.Time `bson:"deliveredAt,omitempty"`
+}
+
+func NewRenderJob(documentID primitive.ObjectID, variables map[string]any, callback string) *RenderJob {
+	job := &RenderJob{
+		ID:         primitive.NewObjectID(),
+		DocumentID: documentID,
+		Variables:  variables,
+		Status:     QUEUED,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if callback != "" {
+		job.Webhook = &Webhook{URL: callback}
+	}
+
+	return job
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+const (
+	// PermissionPreview lets a request see documents that are not published.
+	PermissionPreview = "templates:preview"
+
+	// PermissionPublish lets a request publish documents in review and archive published ones.
+	PermissionPublish = "templates:publish"
+)
+
+// Principal is the user behind a request, as asserted by the gateway.
+type Principal struct {
+	Subject     string
+	Permissions []string
+}
+
+// Can reports whether the principal has permission.
+func (p Principal) Can(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
+}
+
+type (
+	principalKey struct{}
+	previewKey   struct{}
+)
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, or an anonymous one without permissions.
+func FromContext(ctx context.Context) Principal {
+	p, _ := ctx.Value(principalKey{}).(Principal)
+	return p
+}
+
+// WithPreview marks ctx as previewing documents that are not published. Callers check
+// PermissionPreview first.
+func WithPreview(ctx context.Context) context.Context {
+	return context.WithValue(ctx, previewKey{}, true)
+}
+
+// Previewing reports whether ctx previews documents that are not published.
+func Previewing(ctx context.Context) bool {
+	preview, _ := ctx.Value(previewKey{}).(bool)
+	return preview
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/config"
+)
+
+var (
+	ErrNoToken      = errors.New("bearer token is required")
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+// leeway tolerates clock skew between the issuer and this service.
+const leeway = 30 * time.Second
+
+// TokenVerifier checks the signature, issuer, audience and validity period of bearer tokens.
+type TokenVerifier interface {
+	// Verify returns the claims of the bearer token of an Authorization header, failing with
+	// ErrNoToken without one and ErrInvalidToken when it cannot be trusted.
+	Verify(header string) (map[string]any, error)
+
+	// Enabled reports whether a key is configured, so that claims can be trusted.
+	Enabled() bool
+}
+
+type tokenVerifier struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	issuer    string
+	audience  string
+	now       func() time.Time
+}
+
+// NewTokenVerifier verifies HS256/384/512 tokens with the shared secret and RS256/384/512
+// tokens with the public key, whichever are configured. Unsigned tokens, tokens without an
+// expiry and tokens issued by or for others are always rejected.
+func NewTokenVerifier(cfg config.TokenConfig) (TokenVerifier, error) {
+	v := &tokenVerifier{issuer: cfg.Issuer, audience: cfg.Audience, now: time.Now}
+
+	if cfg.Secret != "" {
+		v.secret = []byte(cfg.Secret)
+	}
+
+	if cfg.PublicKeyFile != "" {
+		encoded, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+		}
+		v.publicKey, err = parsePublicKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func (v *tokenVerifier) Enabled() bool {
+	return v.secret != nil || v.publicKey != nil
+}
+
+func (v *tokenVerifier) Verify(header string) (map[string]any, error) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return nil, ErrNoToken
+	}
+
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var head struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return nil, fmt.Errorf("%w: malformed header: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	if err := v.verifySignature(head.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims: %v", ErrInvalidToken, err)
+	}
+
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrInvalidToken)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if nbf, ok := claims["nbf"]; ok {
+		nbf, ok := nbf.(float64)
+		if !ok || now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+			return nil, fmt.Errorf("%w: token not valid yet", ErrInvalidToken)
+		}
+	}
+
+	if iss, _ := claims["iss"].(string); iss != v.issuer {
+		return nil, fmt.Errorf("%w: token issued by %q", ErrInvalidToken, iss)
+	}
+	if !hasAudience(claims["aud"], v.audience) {
+		return nil, fmt.Errorf("%w: token not issued for %q", ErrInvalidToken, v.audience)
+	}
+
+	return claims, nil
+}
+
+// hasAudience reports whether the aud claim, a string or a list of strings, names audience.
+func hasAudience(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *tokenVerifier) verifySignature(alg, signed string, signature []byte) error {
+	var (
+		newHash func() hash.Hash
+		hashID  crypto.Hash
+	)
+	switch alg[min(2, len(alg)):] {
+	case "256":
+		newHash, hashID = sha256.New, crypto.SHA256
+	case "384":
+		newHash, hashID = sha512.New384, crypto.SHA384
+	case "512":
+		newHash, hashID = sha512.New, crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	switch {
+	case strings.HasPrefix(alg, "HS") && v.secret != nil:
+		mac := hmac.New(newHash, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+
+	case strings.HasPrefix(alg, "RS") && v.publicKey != nil:
+		h := newHash()
+		h.Write([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(v.publicKey, hashID, h.Sum(nil), signature); err != nil {
+			return errors.New("signature mismatch")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("no key configured for algorithm %q", alg)
+	}
+}
+
+func decodeSegment(segment string, v any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decoded, v)
+}
+
+func parsePublicKey(encoded []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(encoded)
+	if block == nil {
+		return nil, errors.New("JWT public key is not PEM encoded")
+	}
+
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, errors.New("JWT public key certificate is not RSA")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("JWT public key is not RSA")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/config"
+)
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+var (
+	issuer   = "https://issuer.test"
+	audience = "template-service"
+)
+
+// withDefaults adds a valid issuer, audience and expiry to claims, unless set. Claims set to
+// nil are left out.
+func withDefaults(claims map[string]any) map[string]any {
+	merged := map[string]any{"iss": issuer, "aud": audience, "exp": time.Now().Add(time.Hour).Unix()}
+	for k, v := range claims {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func hmacToken(t *testing.T, secret string, claims map[string]any) string {
+	t.Helper()
+	signed := segment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(t, withDefaults(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyHMAC(t *testing.T) {
+	verifier, err := NewTokenVerifier(config.TokenConfig{Secret: "s3cret", Issuer: issuer, Audience: audience})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := verifier.Verify("Bearer " + hmacToken(t, "s3cret", map[string]any{"tenant": "billing"}))
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if claims["tenant"] != "billing" {
+		t.Fatalf("tenant = %v, want billing", claims["tenant"])
+	}
+
+	unsigned := segment(t, map[string]string{"alg": "none"}) + "." + segment(t, withDefaults(map[string]any{"tenant": "billing"})) + "."
+	expired := hmacToken(t, "s3cret", map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})
+	token := func(claims map[string]any) string { return "Bearer " + hmacToken(t, "s3cret", claims) }
+
+	for name, tc := range map[string]struct {
+		header string
+		want   error
+	}{
+		"missing":      {"", ErrNoToken},
+		"not bearer":   {"Basic abc", ErrNoToken},
+		"wrong secret": {"Bearer " + hmacToken(t, "other", map[string]any{"tenant": "billing"}), ErrInvalidToken},
+		"unsigned":     {"Bearer " + unsigned, ErrInvalidToken},
+		"expired":      {"Bearer " + expired, ErrInvalidToken},
+		"malformed":    {"Bearer abc.def", ErrInvalidToken},
+		"no expiry":    {token(map[string]any{"exp": nil}), ErrInvalidToken},
+		"not yet":      {token(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()}), ErrInvalidToken},
+		"no issuer":    {token(map[string]any{"iss": nil}), ErrInvalidToken},
+		"other issuer": {token(map[string]any{"iss": "https://other.test"}), ErrInvalidToken},
+		"no audience":  {token(map[string]any{"aud": nil}), ErrInvalidToken},
+		"other aud":    {token(map[string]any{"aud": "billing-service"}), ErrInvalidToken},
+		"other auds":   {token(map[string]any{"aud": []string{"billing-service", "hr-service"}}), ErrInvalidToken},
+	} {
+		if _, err := verifier.Verify(tc.header); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	if _, err := verifier.Verify(token(map[string]any{"aud": []string{"billing-service", audience}})); err != nil {
+		t.Fatalf("token for several audiences rejected: %v", err)
+	}
+}
+
+func TestVerifyRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := NewTokenVerifier(config.TokenConfig{PublicKeyFile: path, Issuer: issuer, Audience: audience})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := segment(t, map[string]string{"alg": "RS256"}) + "." + segment(t, withDefaults(map[string]any{"sub": "ann"}))
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	if _, err := verifier.Verify("Bearer " + token); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	// HMAC tokens cannot be forged with the public key as the secret.
+	if _, err := verifier.Verify("Bearer " + hmacToken(t, string(der), map[string]any{"sub": "ann"})); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("HMAC token accepted by an RSA verifier: %v", err)
+	}
+}
@@ -1,11 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/logger"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
 )
 
 type Config struct {
@@ -13,10 +17,26 @@ type Config struct {
 	MongoDB DBConfig
 	AWS     AWSConfig
 	Logger  LogConfig
+	Tenant  TenantConfig
+	Auth    AuthConfig
+	Token   TokenConfig
+	Upload  UploadConfig
+	Scanner ScannerConfig
+	HTML    HTMLConfig
+	Tracing TracingConfig
+	Cache   CacheConfig
+	Extract ExtractConfig
+	Lint    LintConfig
+	OCR     OCRConfig
+	Jobs    JobsConfig
+	PDF     PDFConfig
+	Render  RenderConfig
 }
 
 type AppConfig struct {
-	Port string
+	Port            string
+	ShutdownTimeout time.Duration
+	HealthTimeout   time.Duration
 }
 
 type DBConfig struct {
@@ -32,18 +52,115 @@ type AWSConfig struct {
 	S3BucketName      string
 }
 
-type LogConfig struct {
-	Format     string
-	TimeFormat string
-	TimeZone   string
+type TenantConfig struct {
+	Header    string
+	Claim     string
+	Default   string
+	Buckets   map[string]string
+	Databases map[string]string
+}
+
+// TokenConfig holds the keys verifying bearer tokens: a shared secret for HMAC tokens and
+// a PEM public key, or certificate, for RSA tokens. Tokens must be issued by Issuer for
+// Audience, this service.
+type TokenConfig struct {
+	Secret        string
+	PublicKeyFile string
+	Issuer        string
+	Audience      string
+}
+
+// AuthConfig locates the user and the permissions of a request: claims of the verified
+// bearer token when configured, otherwise headers, which are only read when TrustHeaders
+// states that a gateway sets them and strips them from client requests.
+type AuthConfig struct {
+	UserHeader        string
+	PermissionsHeader string
+	TrustHeaders      bool
+	SubjectClaim      string
+	PermissionsClaim  string
+}
+
+type UploadConfig struct {
+	MaxSizes     map[model.ContentType]int64
+	MaxBatchRows int64
+}
+
+type ScannerConfig struct {
+	Driver        string
+	ClamAVAddress string
+	ClamAVTimeout time.Duration
+}
+
+type HTMLConfig struct {
+	Policy            string
+	AllowedTags       []string
+	AllowedAttributes []string
+}
+
+type TracingConfig struct {
+	Exporter    string
+	ServiceName string
+	SampleRatio float64
+}
+
+type CacheConfig struct {
+	Driver      string
+	TTL         time.Duration
+	MaxEntries  int64
+	MaxBytes    int64
+	RedisURL    string
+	RedisPrefix string
+}
+
+type ExtractConfig struct {
+	ReextractOnStart bool
+	Workers          int64
+	MaxPages         int64
+	MaxBytes         int64
+	Timeout          time.Duration
 }
 
-func (l LogConfig) NewFiberLogger() fiber.Handler {
-	return logger.New(logger.Config{
-		Format:     l.Format,
-		TimeFormat: l.TimeFormat,
-		TimeZone:   l.TimeZone,
-	})
+type LintConfig struct {
+	Severities map[string]string
+	BlockOn    string
+	Strict     bool
+}
+
+type OCRConfig struct {
+	Driver        string
+	TesseractPath string
+	Languages     string
+	Timeout       time.Duration
+}
+
+type RenderConfig struct {
+	MaxIncludeDepth int64
+	MaxIncludeBytes int64
+}
+
+type PDFConfig struct {
+	LicenseKey string
+}
+
+type JobsConfig struct {
+	Workers         int64
+	PollInterval    time.Duration
+	Lease           time.Duration
+	MaxAttempts     int64
+	ResultLifetime  time.Duration
+	WebhookSecret   string
+	WebhookTimeout  time.Duration
+	WebhookAttempts int64
+	WebhookBackoff  time.Duration
+
+	// WebhookAllowedNetworks are internal networks callbacks may nevertheless target.
+	WebhookAllowedNetworks []netip.Prefix
+}
+
+type LogConfig struct {
+	Level  string
+	Levels map[string]string
 }
 
 func Load() (*Config, error) {
@@ -52,6 +169,16 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	shutdownTimeout, err := GetDuration("SHUTDOWN_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	healthTimeout, err := GetDuration("HEALTH_TIMEOUT", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
 	url, err := Get("MONGO_URL", "")
 	if err != nil {
 		return nil, err
@@ -87,23 +214,274 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	loggerFormat, err := Get("LOGGER_FORMAT", "[${time}] ${status} - ${method} ${path} ${latency}\n")
+	logLevel, err := Get("LOG_LEVEL", "info")
+	if err != nil {
+		return nil, err
+	}
+
+	logLevels, err := ParseMap(GetOptional("LOG_LEVELS"))
+	if err != nil {
+		return nil, err
+	}
+
+	tenantHeader, err := Get("TENANT_HEADER", "X-Tenant-ID")
+	if err != nil {
+		return nil, err
+	}
+
+	authUserHeader, err := Get("AUTH_USER_HEADER", "X-User-ID")
+	if err != nil {
+		return nil, err
+	}
+
+	authPermissionsHeader, err := Get("AUTH_PERMISSIONS_HEADER", "X-User-Permissions")
+	if err != nil {
+		return nil, err
+	}
+
+	authTrustHeaders, err := GetBool("AUTH_TRUST_HEADERS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	token := TokenConfig{
+		Secret:        GetOptional("JWT_SECRET"),
+		PublicKeyFile: GetOptional("JWT_PUBLIC_KEY_FILE"),
+		Issuer:        GetOptional("JWT_ISSUER"),
+		Audience:      GetOptional("JWT_AUDIENCE"),
+	}
+
+	// Tokens signed with the keys for other services must not be accepted.
+	if (token.Secret != "" || token.PublicKeyFile != "") && (token.Issuer == "" || token.Audience == "") {
+		return nil, errors.New("JWT_SECRET and JWT_PUBLIC_KEY_FILE require JWT_ISSUER and JWT_AUDIENCE")
+	}
+
+	// Claims are only trusted from verified tokens.
+	if token.Secret == "" && token.PublicKeyFile == "" {
+		for _, claim := range []string{"TENANT_CLAIM", "AUTH_SUBJECT_CLAIM", "AUTH_PERMISSIONS_CLAIM"} {
+			if GetOptional(claim) != "" {
+				return nil, fmt.Errorf("%s requires JWT_SECRET or JWT_PUBLIC_KEY_FILE", claim)
+			}
+		}
+	}
+
+	tenantBuckets, err := ParseMap(GetOptional("TENANT_S3_BUCKETS"))
+	if err != nil {
+		return nil, err
+	}
+
+	tenantDatabases, err := ParseMap(GetOptional("TENANT_DATABASES"))
+	if err != nil {
+		return nil, err
+	}
+
+	maxSizes := make(map[model.ContentType]int64)
+	for contentType, fallback := range map[model.ContentType]int64{
+		model.PDF:        20 << 20,
+		model.HTML:       2 << 20,
+		model.PLAIN_TEXT: 2 << 20,
+		model.IMAGE:      10 << 20,
+	} {
+		maxSizes[contentType], err = GetInt64("UPLOAD_MAX_SIZE_"+string(contentType), fallback)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	batchMaxRows, err := GetInt64("BATCH_MAX_ROWS", 10000)
+	if err != nil {
+		return nil, err
+	}
+
+	scannerDriver, err := Get("SCANNER", "noop")
+	if err != nil {
+		return nil, err
+	}
+
+	clamAVAddress, err := Get("CLAMAV_ADDRESS", "localhost:3310")
+	if err != nil {
+		return nil, err
+	}
+
+	clamAVTimeout, err := GetDuration("CLAMAV_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlPolicy, err := Get("HTML_POLICY", "ugc")
+	if err != nil {
+		return nil, err
+	}
+
+	tracingExporter, err := Get("TRACING_EXPORTER", "none")
+	if err != nil {
+		return nil, err
+	}
+
+	tracingServiceName, err := Get("TRACING_SERVICE_NAME", "template-service")
 	if err != nil {
 		return nil, err
 	}
 
-	loggerTimeFormat, err := Get("LOGGER_TIME_FORMAT", "2006-01-02 15:04:05")
+	tracingSampleRatio, err := GetFloat64("TRACING_SAMPLE_RATIO", 1)
 	if err != nil {
 		return nil, err
 	}
 
-	loggerTimeZone, err := Get("LOGGER_TIME_ZONE", "Local")
+	cacheDriver, err := Get("CACHE_DRIVER", "none")
+	if err != nil {
+		return nil, err
+	}
+
+	cacheTTL, err := GetDuration("CACHE_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMaxEntries, err := GetInt64("CACHE_MAX_ENTRIES", 10000)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMaxBytes, err := GetInt64("CACHE_MAX_BYTES", 256<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	redisPrefix, err := Get("REDIS_PREFIX", "template-service:")
+	if err != nil {
+		return nil, err
+	}
+
+	reextractOnStart, err := GetBool("REEXTRACT_ON_START", false)
+	if err != nil {
+		return nil, err
+	}
+
+	extractWorkers, err := GetInt64("EXTRACT_WORKERS", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	extractMaxPages, err := GetInt64("EXTRACT_MAX_PAGES", 500)
+	if err != nil {
+		return nil, err
+	}
+
+	extractMaxBytes, err := GetInt64("EXTRACT_MAX_BYTES", 50<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	extractTimeout, err := GetDuration("EXTRACT_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	lintSeverities, err := ParseMap(GetOptional("LINT_SEVERITIES"))
+	if err != nil {
+		return nil, err
+	}
+
+	lintBlockOn, err := Get("LINT_BLOCK_ON", "error")
+	if err != nil {
+		return nil, err
+	}
+
+	variablesMode, err := Get("VARIABLES_MODE", "merge")
+	if err != nil {
+		return nil, err
+	}
+
+	if variablesMode != "merge" && variablesMode != "strict" {
+		return nil, fmt.Errorf("invalid VARIABLES_MODE: %s (must be merge or strict)", variablesMode)
+	}
+
+	ocrDriver, err := Get("OCR", "none")
+	if err != nil {
+		return nil, err
+	}
+
+	tesseractPath, err := Get("TESSERACT_PATH", "tesseract")
+	if err != nil {
+		return nil, err
+	}
+
+	ocrLanguages, err := Get("OCR_LANGUAGES", "eng")
+	if err != nil {
+		return nil, err
+	}
+
+	ocrTimeout, err := GetDuration("OCR_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	renderWorkers, err := GetInt64("RENDER_WORKERS", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	renderPollInterval, err := GetDuration("RENDER_POLL_INTERVAL", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	renderLease, err := GetDuration("RENDER_JOB_LEASE", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	renderMaxAttempts, err := GetInt64("RENDER_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	renderResultLifetime, err := GetDuration("RENDER_RESULT_URL_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookTimeout, err := GetDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookAttempts, err := GetInt64("WEBHOOK_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookBackoff, err := GetDuration("WEBHOOK_BACKOFF", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhookAllowedNetworks []netip.Prefix
+	for _, network := range ParseList(GetOptional("WEBHOOK_ALLOWED_NETWORKS")) {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WEBHOOK_ALLOWED_NETWORKS entry %q: %w", network, err)
+		}
+		webhookAllowedNetworks = append(webhookAllowedNetworks, prefix)
+	}
+
+	maxIncludeDepth, err := GetInt64("INCLUDE_MAX_DEPTH", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	maxIncludeBytes, err := GetInt64("INCLUDE_MAX_BYTES", 1<<20)
 	if err != nil {
 		return nil, err
 	}
 
 	cfg := &Config{
-		App: AppConfig{Port: port},
+		App: AppConfig{
+			Port:            port,
+			ShutdownTimeout: shutdownTimeout,
+			HealthTimeout:   healthTimeout,
+		},
 		MongoDB: DBConfig{
 			URL: url,
 			DB:  db,
@@ -116,9 +494,88 @@ func Load() (*Config, error) {
 			S3BucketName:      awsBucket,
 		},
 		Logger: LogConfig{
-			Format:     loggerFormat,
-			TimeFormat: loggerTimeFormat,
-			TimeZone:   loggerTimeZone,
+			Level:  logLevel,
+			Levels: logLevels,
+		},
+		Tenant: TenantConfig{
+			Header:    tenantHeader,
+			Claim:     GetOptional("TENANT_CLAIM"),
+			Default:   GetOptional("TENANT_DEFAULT"),
+			Buckets:   tenantBuckets,
+			Databases: tenantDatabases,
+		},
+		Token: token,
+		Auth: AuthConfig{
+			UserHeader:        authUserHeader,
+			PermissionsHeader: authPermissionsHeader,
+			TrustHeaders:      authTrustHeaders,
+			SubjectClaim:      GetOptional("AUTH_SUBJECT_CLAIM"),
+			PermissionsClaim:  GetOptional("AUTH_PERMISSIONS_CLAIM"),
+		},
+		Upload: UploadConfig{
+			MaxSizes:     maxSizes,
+			MaxBatchRows: batchMaxRows,
+		},
+		Scanner: ScannerConfig{
+			Driver:        scannerDriver,
+			ClamAVAddress: clamAVAddress,
+			ClamAVTimeout: clamAVTimeout,
+		},
+		HTML: HTMLConfig{
+			Policy:            htmlPolicy,
+			AllowedTags:       ParseList(GetOptional("HTML_ALLOWED_TAGS")),
+			AllowedAttributes: ParseList(GetOptional("HTML_ALLOWED_ATTRIBUTES")),
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracingExporter,
+			ServiceName: tracingServiceName,
+			SampleRatio: tracingSampleRatio,
+		},
+		Cache: CacheConfig{
+			Driver:      cacheDriver,
+			TTL:         cacheTTL,
+			MaxEntries:  cacheMaxEntries,
+			MaxBytes:    cacheMaxBytes,
+			RedisURL:    GetOptional("REDIS_URL"),
+			RedisPrefix: redisPrefix,
+		},
+		Extract: ExtractConfig{
+			ReextractOnStart: reextractOnStart,
+			Workers:          extractWorkers,
+			MaxPages:         extractMaxPages,
+			MaxBytes:         extractMaxBytes,
+			Timeout:          extractTimeout,
+		},
+		Lint: LintConfig{
+			Severities: lintSeverities,
+			BlockOn:    lintBlockOn,
+			Strict:     variablesMode == "strict",
+		},
+		OCR: OCRConfig{
+			Driver:        ocrDriver,
+			TesseractPath: tesseractPath,
+			Languages:     ocrLanguages,
+			Timeout:       ocrTimeout,
+		},
+		Jobs: JobsConfig{
+			Workers:         renderWorkers,
+			PollInterval:    renderPollInterval,
+			Lease:           renderLease,
+			MaxAttempts:     renderMaxAttempts,
+			ResultLifetime:  renderResultLifetime,
+			WebhookSecret:   GetOptional("WEBHOOK_SECRET"),
+			WebhookTimeout:  webhookTimeout,
+			WebhookAttempts: webhookAttempts,
+			WebhookBackoff:  webhookBackoff,
+
+			WebhookAllowedNetworks: webhookAllowedNetworks,
+		},
+		PDF: PDFConfig{
+			LicenseKey: GetOptional("UNIDOC_LICENSE_API_KEY"),
+		},
+		Render: RenderConfig{
+			MaxIncludeDepth: maxIncludeDepth,
+			MaxIncludeBytes: maxIncludeBytes,
 		},
 	}
 
@@ -136,3 +593,92 @@ func Get(key string, fallback string) (string, error) {
 
 	return "", fmt.Errorf("%s is required", key)
 }
+
+func GetInt64(key string, fallback int64) (int64, error) {
+	value, err := Get(key, strconv.FormatInt(fallback, 10))
+	if err != nil {
+		return 0, err
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+
+	return parsed, nil
+}
+
+func GetFloat64(key string, fallback float64) (float64, error) {
+	value, err := Get(key, strconv.FormatFloat(fallback, 'f', -1, 64))
+	if err != nil {
+		return 0, err
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+
+	return parsed, nil
+}
+
+func GetDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value, err := Get(key, fallback.String())
+	if err != nil {
+		return 0, err
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration: %w", key, err)
+	}
+
+	return parsed, nil
+}
+
+func GetBool(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean: %w", key, err)
+	}
+
+	return parsed, nil
+}
+
+func GetOptional(key string) string {
+	return os.Getenv(key)
+}
+
+// ParseList parses a comma separated list, skipping empty items.
+func ParseList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// ParseMap parses a comma separated list of key=value pairs, e.g. "a=bucket-a,b=bucket-b".
+func ParseMap(value string) (map[string]string, error) {
+	result := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+			return nil, fmt.Errorf("invalid key=value pair: %q", pair)
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+
+	return result, nil
+}
@@ -2,10 +2,49 @@ package config
 
 import (
 	"fmt"
+	"mime/multipart"
+	"regexp"
 	"strings"
 
 	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
 	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gofiber/fiber/v3"
+	unipdfmodel "github.com/unidoc/unipdf/v3/model"
+)
+
+// allowedMIMETypes lists, per declared content type, the sniffed MIME types accepted for
+// uploads, along with their subtypes, e.g. text/csv or application/json for text/plain. HTML
+// accepts text/plain too, as fragments without a document structure are detected as such.
+var allowedMIMETypes = map[model.ContentType][]string{
+	model.PDF:        {"application/pdf"},
+	model.HTML:       {"text/html", "application/xhtml+xml", "text/plain"},
+	model.PLAIN_TEXT: {"text/plain"},
+	model.IMAGE:      {"image/png", "image/jpeg", "image/gif", "image/webp", "image/tiff", "image/bmp"},
+}
+
+// deniedMIMETypes lists the subtypes of allowed MIME types that are nevertheless rejected:
+// markup is not plain text, and would be stored without being sanitised.
+var deniedMIMETypes = map[model.ContentType][]string{
+	model.PLAIN_TEXT: {"text/html", "application/xhtml+xml", "image/svg+xml"},
+}
+
+var (
+	identifier = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+	// slug is the format of template slugs, e.g. invoice-standard, and of alias labels.
+	slug     = regexp.MustCompile(`^[a-z0-9]+(?:[-_.][a-z0-9]+)*$`)
+	objectID = regexp.MustCompile(`^[0-9a-fA-F]{24}$`)
+
+	// metadataKey excludes the dots and dollar signs Mongo gives a meaning to in field names.
+	metadataKey = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+const (
+	maxTags          = 50
+	maxTagLength     = 64
+	maxMetadataKeys  = 50
+	maxMetadataValue = 1024
 )
 
 type Validator interface {
@@ -21,6 +60,14 @@ func Validate(d *dto.InsertDocument) error {
 		return fmt.Errorf("name is required")
 	}
 
+	if d.Slug != "" && (len(d.Slug) > 100 || !slug.MatchString(d.Slug) || objectID.MatchString(d.Slug)) {
+		return fmt.Errorf("invalid slug: %q (lowercase letters, digits and -_. separators, not an object id)", d.Slug)
+	}
+
+	if err := validateLabels(d); err != nil {
+		return err
+	}
+
 	if !d.Type.IsValid() {
 		return fmt.Errorf("invalid document type: %s (must be STATIC or TEMPLATE)", d.Type)
 	}
@@ -37,9 +84,14 @@ func Validate(d *dto.InsertDocument) error {
 		if d.Source != model.FILE {
 			return fmt.Errorf("IMAGE content type requires FILE source")
 		}
-		if d.Type == model.TEMPLATE {
-			return fmt.Errorf("IMAGE content type cannot be used with TEMPLATE documents")
-		}
+	}
+
+	if strings.TrimSpace(d.Layout) != "" && (d.Type != model.TEMPLATE || d.Source != model.TEXT) {
+		return fmt.Errorf("layout requires a TEMPLATE with TEXT source")
+	}
+
+	if err := ValidateBody(d.Body); err != nil {
+		return err
 	}
 
 	if d.Source == model.TEXT {
@@ -50,3 +102,152 @@ func Validate(d *dto.InsertDocument) error {
 
 	return nil
 }
+
+// validateLabels checks the tags, category and metadata used to organise documents.
+func validateLabels(d *dto.InsertDocument) error {
+	if len(d.Tags) > maxTags {
+		return fmt.Errorf("too many tags: %d (at most %d)", len(d.Tags), maxTags)
+	}
+	for _, tag := range d.Tags {
+		if tag = strings.TrimSpace(tag); tag == "" || len(tag) > maxTagLength || strings.Contains(tag, ",") {
+			return fmt.Errorf("invalid tag: %q (1 to %d characters, no commas)", tag, maxTagLength)
+		}
+	}
+
+	if len(d.Category) > maxTagLength {
+		return fmt.Errorf("invalid category: longer than %d characters", maxTagLength)
+	}
+
+	if len(d.Metadata) > maxMetadataKeys {
+		return fmt.Errorf("too many metadata keys: %d (at most %d)", len(d.Metadata), maxMetadataKeys)
+	}
+	for key, value := range d.Metadata {
+		if !metadataKey.MatchString(key) {
+			return fmt.Errorf("invalid metadata key: %q (letters, digits, _ and -)", key)
+		}
+		if len(value) > maxMetadataValue {
+			return fmt.Errorf("metadata %s is longer than %d characters", key, maxMetadataValue)
+		}
+	}
+
+	return nil
+}
+
+// ValidateAlias checks that an alias is a slug and a label joined by @, e.g. invoice@prod.
+func ValidateAlias(alias string) error {
+	name, label, ok := strings.Cut(alias, "@")
+	if !ok || len(alias) > 100 || !slug.MatchString(name) || !slug.MatchString(label) {
+		return fmt.Errorf("invalid alias: %q (must be name@label, e.g. invoice@prod)", alias)
+	}
+	return nil
+}
+
+// ValidateBody checks the variables declared in a body.
+func ValidateBody(body *dto.InsertBody) error {
+	if body == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(body.Variables))
+	for _, v := range body.Variables {
+		if !identifier.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name: %q", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable %s is declared more than once", v.Name)
+		}
+		if !v.Type.IsValid() {
+			return fmt.Errorf("invalid type for variable %s: %s (must be STRING, NUMBER, BOOLEAN or DATE)", v.Name, v.Type)
+		}
+		seen[v.Name] = true
+	}
+
+	return nil
+}
+
+// ValidateUpload checks the payload size against the configured limits and, for files,
+// that the sniffed bytes match the declared content type and that PDFs can be parsed.
+func ValidateUpload(d *dto.InsertDocument, file *multipart.FileHeader, cfg UploadConfig) error {
+	limit, ok := cfg.MaxSizes[d.ContentType]
+	if !ok {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type: %s", d.ContentType))
+	}
+
+	if d.Source == model.TEXT {
+		if d.Body != nil && d.Body.Text != nil && int64(len(*d.Body.Text)) > limit {
+			return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("text exceeds the %d bytes limit for %s", limit, d.ContentType))
+		}
+		return nil
+	}
+
+	if file == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "file is required for FILE source")
+	}
+
+	if file.Size > limit {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the %d bytes limit for %s", limit, d.ContentType))
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to open file: "+err.Error())
+	}
+	defer src.Close()
+
+	detected, err := mimetype.DetectReader(src)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to read file: "+err.Error())
+	}
+
+	if !matchesMIME(detected, d.ContentType) {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, fmt.Sprintf("file content is %s, which does not match declared content type %s", detected.String(), d.ContentType))
+	}
+
+	if d.ContentType != model.PDF {
+		return nil
+	}
+
+	if _, err := src.Seek(0, 0); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "failed to read file: "+err.Error())
+	}
+
+	pdf, err := unipdfmodel.NewPdfReader(src)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "corrupt pdf: "+err.Error())
+	}
+
+	encrypted, err := pdf.IsEncrypted()
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "corrupt pdf: "+err.Error())
+	}
+
+	if encrypted {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "encrypted pdf files are not supported")
+	}
+
+	if _, err := pdf.GetNumPages(); err != nil {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "corrupt pdf: "+err.Error())
+	}
+
+	return nil
+}
+
+// matchesMIME matches the detected type exactly: parents are not considered, as every text
+// format descends from text/plain and every binary one from application/octet-stream.
+// matchesMIME reports whether detected, or the type it is a subtype of, is allowed for
+// contentType, walking up from the most specific type.
+func matchesMIME(detected *mimetype.MIME, contentType model.ContentType) bool {
+	for m := detected; m != nil; m = m.Parent() {
+		for _, denied := range deniedMIMETypes[contentType] {
+			if m.Is(denied) {
+				return false
+			}
+		}
+		for _, allowed := range allowedMIMETypes[contentType] {
+			if m.Is(allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
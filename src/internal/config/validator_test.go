@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+func TestMatchesMIME(t *testing.T) {
+	for _, tc := range []struct {
+		content     string
+		contentType model.ContentType
+		want        bool
+	}{
+		{"<html><body><p>Hello</p></body></html>", model.HTML, true},
+		{"<p>Hello {{ name }}</p>", model.HTML, true},
+		{"Hello {{ name }}", model.PLAIN_TEXT, true},
+		{`{"name": "invoice"}`, model.PLAIN_TEXT, true},
+		{"name,total\nann,10\nbob,20\n", model.PLAIN_TEXT, true},
+		{`<?xml version="1.0"?><invoice><total>10</total></invoice>`, model.PLAIN_TEXT, true},
+		{"<html><body></body></html>", model.PLAIN_TEXT, false},
+		{`<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"><body></body></html>`, model.PLAIN_TEXT, false},
+		{`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`, model.PLAIN_TEXT, false},
+		{`<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"><body><p>Hello</p></body></html>`, model.HTML, true},
+		{"%PDF-1.4\n", model.PLAIN_TEXT, false},
+		{"%PDF-1.4\n", model.PDF, true},
+	} {
+		detected := mimetype.Detect([]byte(tc.content))
+		if got := matchesMIME(detected, tc.contentType); got != tc.want {
+			t.Errorf("%q (%s) as %s: got %v, want %v", tc.content, detected, tc.contentType, got, tc.want)
+		}
+	}
+}
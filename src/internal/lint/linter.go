@@ -0,0 +1,209 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+)
+
+const (
+	UnbalancedBraces   = "unbalanced-braces"
+	InvalidIdentifier  = "invalid-identifier"
+	SplitPlaceholder   = "split-placeholder"
+	UnusedVariable     = "unused-variable"
+	UndeclaredVariable = "undeclared-variable"
+)
+
+const (
+	Error   = "error"
+	Warning = "warning"
+	Off     = "off"
+)
+
+// DefaultSeverities only treats placeholders that can never be filled as errors.
+var DefaultSeverities = map[string]string{
+	UnbalancedBraces:   Warning,
+	InvalidIdentifier:  Error,
+	SplitPlaceholder:   Warning,
+	UnusedVariable:     Warning,
+	UndeclaredVariable: Warning,
+}
+
+var identifier = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// Page is a chunk of extracted text. Number is the 1-based PDF page, or zero for text content.
+// Runs holds the offsets in Text where a new PDF text object starts.
+type Page struct {
+	Number int
+	Text   string
+	Runs   []int
+}
+
+type Linter interface {
+	Lint(pages []Page, declared []string) *dto.LintReport
+}
+
+type linter struct {
+	severities map[string]string
+	blockOn    string
+	strict     bool
+}
+
+// NewLinter overrides DefaultSeverities with severities (rule=error|warning|off). Reports are
+// blocking when they contain an issue at or above blockOn (error, warning or none).
+//
+// In strict mode declared and extracted variables must match exactly: mismatches are always
+// blocking errors, and templates without declarations have every placeholder reported.
+func NewLinter(severities map[string]string, blockOn string, strict bool) (Linter, error) {
+	merged := make(map[string]string, len(DefaultSeverities))
+	for rule, severity := range DefaultSeverities {
+		merged[rule] = severity
+	}
+
+	for rule, severity := range severities {
+		if _, ok := DefaultSeverities[rule]; !ok {
+			return nil, fmt.Errorf("unknown lint rule: %s", rule)
+		}
+		if severity != Error && severity != Warning && severity != Off {
+			return nil, fmt.Errorf("invalid severity for %s: %s (must be error, warning or off)", rule, severity)
+		}
+		merged[rule] = severity
+	}
+
+	if blockOn != Error && blockOn != Warning && blockOn != "none" {
+		return nil, fmt.Errorf("invalid lint block level: %s (must be error, warning or none)", blockOn)
+	}
+
+	return &linter{severities: merged, blockOn: blockOn, strict: strict}, nil
+}
+
+func (l *linter) Lint(pages []Page, declared []string) *dto.LintReport {
+	report := &dto.LintReport{Issues: []dto.LintIssue{}}
+
+	var used []string
+	first := make(map[string]dto.Position)
+
+	for _, p := range pages {
+		text := p.Text
+		i := 0
+
+		for {
+			opening := strings.Index(text[i:], "{{")
+			closing := strings.Index(text[i:], "}}")
+
+			if closing >= 0 && (opening < 0 || closing < opening) {
+				l.report(report, UnbalancedBraces, "closing braces without opening braces", "", at(p, i+closing))
+				i += closing + 2
+				continue
+			}
+
+			if opening < 0 {
+				break
+			}
+
+			start := i + opening
+			inner := text[start+2:]
+			end := strings.Index(inner, "}}")
+			next := strings.Index(inner, "{{")
+
+			if end < 0 || (next >= 0 && next < end) {
+				l.report(report, UnbalancedBraces, "opening braces are never closed", "", at(p, start))
+				i = start + 2
+				continue
+			}
+
+			name, _, _ := strings.Cut(inner[:end], "|")
+			name = strings.TrimSpace(name)
+			i = start + 2 + end + 2
+
+			if !identifier.MatchString(name) {
+				l.report(report, InvalidIdentifier, fmt.Sprintf("%q is not a valid variable name", name), name, at(p, start))
+				continue
+			}
+
+			if split(p.Runs, start, i) {
+				l.report(report, SplitPlaceholder, "placeholder spans several PDF text runs", name, at(p, start))
+			}
+
+			if _, ok := first[name]; !ok {
+				first[name] = Position(p, start)
+				used = append(used, name)
+			}
+		}
+	}
+
+	if len(declared) == 0 && !l.strict {
+		return report
+	}
+
+	isDeclared := make(map[string]bool, len(declared))
+	for _, name := range declared {
+		isDeclared[name] = true
+		if _, ok := first[name]; !ok {
+			l.report(report, UnusedVariable, fmt.Sprintf("declared variable %q is never used", name), name, nil)
+		}
+	}
+
+	for _, name := range used {
+		if position := first[name]; !isDeclared[name] {
+			l.report(report, UndeclaredVariable, fmt.Sprintf("variable %q is used but not declared", name), name, &position)
+		}
+	}
+
+	return report
+}
+
+func (l *linter) report(report *dto.LintReport, rule, message, variable string, position *dto.Position) {
+	severity := l.severities[rule]
+	if l.strict && (rule == UnusedVariable || rule == UndeclaredVariable) {
+		severity = Error
+		report.Blocking = true
+	}
+
+	if severity == Off {
+		return
+	}
+
+	issue := dto.LintIssue{
+		Rule:     rule,
+		Severity: severity,
+		Message:  message,
+		Variable: variable,
+		Position: position,
+	}
+
+	report.Issues = append(report.Issues, issue)
+	if l.blockOn == Warning || (l.blockOn == Error && severity == Error) {
+		report.Blocking = true
+	}
+}
+
+// Position locates offset in p: its page for PDF pages, its line and column otherwise.
+func Position(p Page, offset int) dto.Position {
+	if p.Number > 0 {
+		return dto.Position{Page: p.Number}
+	}
+
+	before := p.Text[:offset]
+	return dto.Position{
+		Line:   strings.Count(before, "\n") + 1,
+		Column: utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1,
+	}
+}
+
+func at(p Page, offset int) *dto.Position {
+	position := Position(p, offset)
+	return &position
+}
+
+func split(runs []int, start, end int) bool {
+	for _, run := range runs {
+		if run > start && run < end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package lint
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+)
+
+func TestLint(t *testing.T) {
+	text := func(s string) []Page { return []Page{{Text: s}} }
+
+	for _, tc := range []struct {
+		name     string
+		pages    []Page
+		declared []string
+		strict   bool
+		rules    []string
+		severity string
+		blocking bool
+	}{
+		{"unclosed", text("Hello {{ name }"), nil, false, []string{UnbalancedBraces}, Warning, false},
+		{"unclosed, strict", text("Hello {{ name }"), []string{"name"}, true, []string{UnbalancedBraces, UnusedVariable}, "", true},
+		{"unopened", text("Hello name }}"), nil, false, []string{UnbalancedBraces}, Warning, false},
+		{"space in the name", text("Dear {{first name}}"), nil, false, []string{InvalidIdentifier}, Error, true},
+		{"space in the name, strict", text("Dear {{first name}}"), []string{"first_name"}, true, []string{InvalidIdentifier, UnusedVariable}, Error, true},
+		{"split across runs", []Page{{Number: 1, Text: "Dear {{ name }},", Runs: []int{0, 10}}}, nil, false, []string{SplitPlaceholder}, Warning, false},
+		{"split across runs, strict", []Page{{Number: 1, Text: "Dear {{ name }},", Runs: []int{0, 10}}}, []string{"name"}, true, []string{SplitPlaceholder}, Warning, false},
+		{"run boundary at the braces", []Page{{Number: 1, Text: "Dear {{ name }},", Runs: []int{0, 5, 15}}}, nil, false, nil, "", false},
+		{"unused and undeclared", text("{{ name }} owes {{ total }}"), []string{"name", "email"}, false, []string{UnusedVariable, UndeclaredVariable}, Warning, false},
+		{"unused and undeclared, strict", text("{{ name }} owes {{ total }}"), []string{"name", "email"}, true, []string{UnusedVariable, UndeclaredVariable}, Error, true},
+		{"nothing declared", text("{{ name }}"), nil, false, nil, "", false},
+		{"nothing declared, strict", text("{{ name }}"), nil, true, []string{UndeclaredVariable}, Error, true},
+		{"declared and used, strict", text("{{ name | upper }} {{ name }}"), []string{"name"}, true, nil, "", false},
+	} {
+		l, err := NewLinter(nil, Error, tc.strict)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := l.Lint(tc.pages, tc.declared)
+
+		var rules []string
+		for _, issue := range report.Issues {
+			rules = append(rules, issue.Rule)
+			if tc.severity != "" && issue.Severity != tc.severity {
+				t.Errorf("%s: %s is %s, want %s", tc.name, issue.Rule, issue.Severity, tc.severity)
+			}
+		}
+		if !slices.Equal(rules, tc.rules) {
+			t.Errorf("%s: rules = %v, want %v", tc.name, rules, tc.rules)
+		}
+		if report.Blocking != tc.blocking {
+			t.Errorf("%s: blocking = %v, want %v", tc.name, report.Blocking, tc.blocking)
+		}
+	}
+}
+
+func TestLintPositions(t *testing.T) {
+	l, err := NewLinter(nil, Error, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := l.Lint([]Page{{Text: "Dear {{ name }},\n  {{first name}} {{ total"}}, []string{"name"})
+
+	want := []dto.LintIssue{
+		{Rule: InvalidIdentifier, Severity: Error, Variable: "first name", Position: &dto.Position{Line: 2, Column: 3}},
+		{Rule: UnbalancedBraces, Severity: Warning, Position: &dto.Position{Line: 2, Column: 18}},
+	}
+	if len(report.Issues) != len(want) {
+		t.Fatalf("issues = %+v", report.Issues)
+	}
+	for i, issue := range report.Issues {
+		issue.Message = ""
+		if issue.Rule != want[i].Rule || issue.Severity != want[i].Severity || issue.Variable != want[i].Variable || *issue.Position != *want[i].Position {
+			t.Errorf("issue %d = %+v at %+v, want %+v at %+v", i, issue, *issue.Position, want[i], *want[i].Position)
+		}
+	}
+}
+
+func TestLintSeverities(t *testing.T) {
+	l, err := NewLinter(map[string]string{UnbalancedBraces: Error, UndeclaredVariable: Off}, Error, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := l.Lint([]Page{{Text: "{{ name } {{ total }}"}}, []string{"name"})
+	if len(report.Issues) != 2 || report.Issues[0].Rule != UnbalancedBraces || report.Issues[1].Rule != UnusedVariable || !report.Blocking {
+		t.Fatalf("report = %+v", report)
+	}
+
+	if _, err := NewLinter(map[string]string{"no-such-rule": Error}, Error, false); err == nil {
+		t.Fatal("unknown rule accepted")
+	}
+	if _, err := NewLinter(nil, "fatal", false); err == nil {
+		t.Fatal("unknown block level accepted")
+	}
+}
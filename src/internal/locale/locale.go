@@ -0,0 +1,79 @@
+package locale
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+type contextKey struct{}
+
+// WithLocales stores the locales a request prefers, most preferred first.
+func WithLocales(ctx context.Context, tags []string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tags)
+}
+
+// FromContext returns the locales stored in ctx, or none when missing.
+func FromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(contextKey{}).([]string)
+	return tags
+}
+
+// Preferred returns the most preferred locale stored in ctx, or an empty string when missing.
+func Preferred(ctx context.Context) string {
+	if tags := FromContext(ctx); len(tags) > 0 {
+		return tags[0]
+	}
+	return ""
+}
+
+// Normalize returns the canonical form of a BCP 47 language tag, e.g. it-IT for it_it.
+func Normalize(tag string) (string, error) {
+	parsed, err := language.Parse(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if err != nil || parsed == language.Und {
+		return "", fmt.Errorf("invalid locale: %q", tag)
+	}
+	return parsed.String(), nil
+}
+
+// Parse reads an Accept-Language header, or a comma separated list of tags, into canonical
+// tags ordered by preference. Wildcards are dropped.
+func Parse(value string) ([]string, error) {
+	tags, _, err := language.ParseAcceptLanguage(strings.ReplaceAll(value, "_", "-"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid locales %q: %w", value, err)
+	}
+
+	parsed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag == language.Und || tag.String() == "mul" {
+			continue
+		}
+		parsed = append(parsed, tag.String())
+	}
+	return parsed, nil
+}
+
+// Fallbacks expands tags into the chain of locales to look up, each tag followed by the tags
+// left by dropping its subtags in turn: it-IT, it, then the next preferred tag.
+func Fallbacks(tags []string) []string {
+	var chain []string
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		for tag != "" {
+			if !seen[tag] {
+				seen[tag] = true
+				chain = append(chain, tag)
+			}
+			i := strings.LastIndexByte(tag, '-')
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return chain
+}
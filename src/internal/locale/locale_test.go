@@ -0,0 +1,81 @@
+package locale
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  []string
+	}{
+		{"", []string{}},
+		{"it-IT", []string{"it-IT"}},
+		{"it_it", []string{"it-IT"}},
+		{"it-IT,it;q=0.9,en;q=0.5", []string{"it-IT", "it", "en"}},
+		{"en;q=0.5,it-IT", []string{"it-IT", "en"}},
+		{"fr, *;q=0.1", []string{"fr"}},
+		{"de-CH,de", []string{"de-CH", "de"}},
+	} {
+		got, err := Parse(tc.value)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.value, err)
+			continue
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+
+	for _, value := range []string{"it-IT;q=abc", "12345678901", "en;q=0.5;;"} {
+		if got, err := Parse(value); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", value, got)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for value, want := range map[string]string{"it_it": "it-IT", " en-us ": "en-US", "pt-br": "pt-BR", "it": "it"} {
+		if got, err := Normalize(value); err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "und", "not a tag", "it-IT-"} {
+		if got, err := Normalize(value); err == nil {
+			t.Errorf("Normalize(%q) = %q, want an error", value, got)
+		}
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	for _, tc := range []struct {
+		tags []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"it"}, []string{"it"}},
+		{[]string{"it-IT"}, []string{"it-IT", "it"}},
+		{[]string{"zh-Hant-TW"}, []string{"zh-Hant-TW", "zh-Hant", "zh"}},
+		{[]string{"it-IT", "en"}, []string{"it-IT", "it", "en"}},
+		{[]string{"it-IT", "it-CH", "it"}, []string{"it-IT", "it", "it-CH"}},
+		{[]string{"en-GB", "en-US"}, []string{"en-GB", "en", "en-US"}},
+	} {
+		if got := Fallbacks(tc.tags); !slices.Equal(got, tc.want) {
+			t.Errorf("Fallbacks(%v) = %v, want %v", tc.tags, got, tc.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != nil || Preferred(context.Background()) != "" {
+		t.Fatal("locales found in an empty context")
+	}
+
+	ctx := WithLocales(context.Background(), []string{"it-IT", "en"})
+	if Preferred(ctx) != "it-IT" || !slices.Equal(FromContext(ctx), []string{"it-IT", "en"}) {
+		t.Fatalf("locales = %v", FromContext(ctx))
+	}
+}
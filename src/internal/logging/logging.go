@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+var (
+	mu           sync.Mutex
+	defaultLevel = new(slog.LevelVar)
+	levels       = make(map[string]*slog.LevelVar)
+	overrides    = make(map[string]slog.Level)
+)
+
+// For returns the JSON logger of a package. Loggers can be created before Init runs,
+// levels are applied to them as soon as it does.
+func For(pkg string) *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+
+	level, ok := levels[pkg]
+	if !ok {
+		level = new(slog.LevelVar)
+		level.Set(defaultLevel.Level())
+		if override, ok := overrides[pkg]; ok {
+			level.Set(override)
+		}
+		levels[pkg] = level
+	}
+
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	return slog.New(&contextHandler{Handler: handler}).With(slog.String("package", pkg))
+}
+
+// Init sets the default level and the per-package overrides, e.g. {"repository": "warn"}.
+func Init(level string, packages map[string]string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	parsedOverrides := make(map[string]slog.Level, len(packages))
+	for pkg, l := range packages {
+		if parsedOverrides[pkg], err = ParseLevel(l); err != nil {
+			return fmt.Errorf("invalid level for package %s: %w", pkg, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultLevel.Set(parsed)
+	overrides = parsedOverrides
+
+	for pkg, l := range levels {
+		l.Set(parsed)
+		if override, ok := overrides[pkg]; ok {
+			l.Set(override)
+		}
+	}
+
+	return nil
+}
+
+func ParseLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return 0, fmt.Errorf("invalid log level: %s", level)
+	}
+	return parsed, nil
+}
+
+// With returns a context whose log lines carry attrs in addition to the ones already set.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the request correlation fields found in ctx to every record.
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if rid := requestid.FromContext(ctx); rid != "" {
+		record.AddAttrs(slog.String("request_id", rid))
+	}
+
+	if t := tenant.Key(ctx); t != "" {
+		record.AddAttrs(slog.String("tenant", t))
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", span.TraceID().String()),
+			slog.String("span_id", span.SpanID().String()),
+		)
+	}
+
+	if attrs, ok := ctx.Value(contextKey{}).([]slog.Attr); ok {
+		record.AddAttrs(attrs...)
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "template_service"
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	factory = promauto.With(Registry)
+
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	S3Operations = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "s3_operations_total",
+		Help:      "S3 operations by operation and result.",
+	}, []string{"operation", "result"})
+
+	S3OperationDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "s3_operation_duration_seconds",
+		Help:      "S3 operation latency by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	S3Bytes = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "s3_bytes_total",
+		Help:      "Bytes transferred to and from S3 by operation.",
+	}, []string{"operation"})
+
+	MongoOperationDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "Mongo operation latency by collection, operation and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"collection", "operation", "result"})
+
+	ExtractionDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "variable_extraction_duration_seconds",
+		Help:      "Variable extraction latency by content type.",
+		Buckets:   []float64{.005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"content_type"})
+
+	PDFPages = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pdf_pages",
+		Help:      "Page count of the PDF files parsed for variable extraction.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+
+	RenderJobs = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "render_jobs_total",
+		Help:      "Render jobs finished or requeued, by resulting status.",
+	}, []string{"status"})
+
+	RenderJobDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "render_job_duration_seconds",
+		Help:      "Time spent by a worker processing a render job.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	WebhookDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook deliveries by result, after retries.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// NewMiddleware records the latency of every request labelled by its route pattern, so
+// that IDs in the path do not explode label cardinality.
+func NewMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		HTTPRequestDuration.WithLabelValues(c.Method(), c.Route().Path, strconv.Itoa(status)).Observe(Since(start))
+		return err
+	}
+}
+
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
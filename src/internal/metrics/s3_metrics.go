@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/clients/aws"
+)
+
+type s3Client struct {
+	aws.S3Client
+}
+
+// NewS3Client wraps client so that every operation is counted, timed and, for uploads
+// and downloads, measured in bytes.
+func NewS3Client(client aws.S3Client) aws.S3Client {
+	return &s3Client{S3Client: client}
+}
+
+func (s *s3Client) EnsureBucketExists(ctx context.Context) error {
+	start := time.Now()
+	err := s.S3Client.EnsureBucketExists(ctx)
+	observeS3("head_bucket", start, err)
+	return err
+}
+
+// Upload measures seekable bodies up front rather than wrapping them, since the SDK needs
+// to seek to sign the payload and would otherwise read it twice.
+func (s *s3Client) Upload(ctx context.Context, key string, body io.Reader) error {
+	start := time.Now()
+
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		size, sizeErr := remaining(seeker)
+		err := s.S3Client.Upload(ctx, key, seeker)
+		observeS3("upload", start, err)
+		if err == nil && sizeErr == nil {
+			S3Bytes.WithLabelValues("upload").Add(float64(size))
+		}
+		return err
+	}
+
+	counter := &countingReader{Reader: body}
+	err := s.S3Client.Upload(ctx, key, counter)
+	observeS3("upload", start, err)
+	S3Bytes.WithLabelValues("upload").Add(float64(counter.n))
+	return err
+}
+
+func (s *s3Client) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	start := time.Now()
+	body, err := s.S3Client.Download(ctx, key)
+	observeS3("download", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &countingReadCloser{ReadCloser: body}, nil
+}
+
+func (s *s3Client) DownloadWithPresignedURL(ctx context.Context, key string, lifetime time.Duration) (string, error) {
+	start := time.Now()
+	url, err := s.S3Client.DownloadWithPresignedURL(ctx, key, lifetime)
+	observeS3("presign", start, err)
+	return url, err
+}
+
+func (s *s3Client) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.S3Client.Delete(ctx, key)
+	observeS3("delete", start, err)
+	return err
+}
+
+func observeS3(operation string, start time.Time, err error) {
+	S3Operations.WithLabelValues(operation, Result(err)).Inc()
+	S3OperationDuration.WithLabelValues(operation).Observe(Since(start))
+}
+
+func remaining(seeker io.Seeker) (int64, error) {
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return end - current, nil
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	S3Bytes.WithLabelValues("download").Add(float64(n))
+	return n, err
+}
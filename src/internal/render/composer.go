@@ -0,0 +1,189 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/unidoc/unipdf/v3/creator"
+	unipdfmodel "github.com/unidoc/unipdf/v3/model"
+)
+
+// Section is a part of a composed PDF. PDF content is imported page by page, IMAGE content
+// is scaled to fit a page and any other content is typeset as text, HTML reduced to its text.
+// Every section starts on a new page.
+type Section struct {
+	Title       string
+	ContentType model.ContentType
+	Content     []byte
+}
+
+type ComposeOptions struct {
+	Bookmarks       bool
+	PageNumbers     bool
+	TableOfContents bool
+}
+
+// Composer concatenates sections into a single PDF.
+type Composer interface {
+	Compose(sections []Section, options ComposeOptions, w io.Writer) error
+}
+
+type composer struct{}
+
+func NewComposer() Composer {
+	return &composer{}
+}
+
+func (p *composer) Compose(sections []Section, options ComposeOptions, w io.Writer) error {
+	c := creator.New()
+
+	// The table of contents comes first but lists where sections start, so they are laid out
+	// once on their own to find their relative start pages and the table once to count its
+	// pages.
+	if options.TableOfContents {
+		starts, err := p.layout(creator.New(), sections)
+		if err != nil {
+			return err
+		}
+
+		pages, err := p.contents(creator.New(), sections, starts, 0)
+		if err != nil {
+			return err
+		}
+
+		if _, err := p.contents(c, sections, starts, pages); err != nil {
+			return err
+		}
+	}
+
+	starts, err := p.layout(c, sections)
+	if err != nil {
+		return err
+	}
+
+	if options.Bookmarks {
+		outline := unipdfmodel.NewOutline()
+		if options.TableOfContents {
+			outline.Add(unipdfmodel.NewOutlineItem("Contents", fitPage(1)))
+		}
+		for i, s := range sections {
+			outline.Add(unipdfmodel.NewOutlineItem(s.Title, fitPage(starts[i])))
+		}
+		c.SetOutlineTree(outline.ToOutlineTree())
+	}
+
+	if options.PageNumbers {
+		c.DrawFooter(func(block *creator.Block, args creator.FooterFunctionArgs) {
+			number := c.NewParagraph(fmt.Sprintf("%d / %d", args.PageNum, args.TotalPages))
+			number.SetWidth(block.Width())
+			number.SetTextAlignment(creator.TextAlignmentCenter)
+			number.SetPos(0, block.Height()/2)
+			_ = block.Draw(number)
+		})
+	}
+
+	return c.Write(w)
+}
+
+// layout draws every section on c and returns the page each one starts on.
+func (p *composer) layout(c *creator.Creator, sections []Section) ([]int, error) {
+	starts := make([]int, len(sections))
+
+	for i, s := range sections {
+		starts[i] = c.Context().Page + 1
+
+		var err error
+		switch s.ContentType {
+		case model.PDF:
+			err = p.importPDF(c, s.Content)
+		case model.IMAGE:
+			err = p.drawImage(c, s.Content)
+		case model.HTML:
+			err = p.drawText(c, HTMLText(string(s.Content)))
+		default:
+			err = p.drawText(c, string(s.Content))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("section %d (%s): %w", i+1, s.Title, err)
+		}
+	}
+
+	return starts, nil
+}
+
+// contents draws the table of contents on c and returns the pages it takes.
+func (p *composer) contents(c *creator.Creator, sections []Section, starts []int, offset int) (int, error) {
+	toc := c.NewTOC("Contents")
+	for i, s := range sections {
+		page := starts[i] + offset
+		toc.Add("", s.Title, strconv.Itoa(page), 1).SetLink(int64(page), 0, 0)
+	}
+
+	c.NewPage()
+	if err := c.Draw(toc); err != nil {
+		return 0, fmt.Errorf("failed to draw table of contents: %w", err)
+	}
+
+	return c.Context().Page, nil
+}
+
+func (p *composer) importPDF(c *creator.Creator, content []byte) error {
+	reader, err := unipdfmodel.NewPdfReader(bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse pdf: %w", err)
+	}
+
+	count, err := reader.GetNumPages()
+	if err != nil {
+		return fmt.Errorf("failed to read pdf page count: %w", err)
+	}
+
+	for n := 1; n <= count; n++ {
+		page, err := reader.GetPage(n)
+		if err != nil {
+			return fmt.Errorf("failed to get pdf page %d: %w", n, err)
+		}
+		if err := c.AddPage(page); err != nil {
+			return fmt.Errorf("failed to add pdf page %d: %w", n, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *composer) drawImage(c *creator.Creator, content []byte) error {
+	c.NewPage()
+
+	img, err := c.NewImageFromData(content)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	ctx := c.Context()
+	if img.Width() > ctx.Width {
+		img.ScaleToWidth(ctx.Width)
+	}
+	if img.Height() > ctx.Height {
+		img.ScaleToHeight(ctx.Height)
+	}
+
+	return c.Draw(img)
+}
+
+func (p *composer) drawText(c *creator.Creator, text string) error {
+	c.NewPage()
+
+	paragraph := c.NewStyledParagraph()
+	paragraph.Append(text)
+	return c.Draw(paragraph)
+}
+
+func fitPage(page int) unipdfmodel.OutlineDest {
+	dest := unipdfmodel.NewOutlineDest(int64(page-1), 0, 0)
+	dest.Mode = "Fit"
+	return dest
+}
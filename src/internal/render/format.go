@@ -0,0 +1,226 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	textnumber "golang.org/x/text/number"
+)
+
+// dateFormat holds the date patterns and names of a language. Patterns use d, dd, M, MM,
+// MMM, MMMM, yy, yyyy and EEEE; anything else is written as is.
+type dateFormat struct {
+	styles map[string]string
+	months [12]string
+	short  [12]string
+	days   [7]string
+}
+
+// dateFormats are keyed by base language; other languages are formatted as English.
+var dateFormats = map[string]dateFormat{
+	"en": {
+		styles: map[string]string{"short": "M/d/yy", "medium": "MMM d, yyyy", "long": "MMMM d, yyyy", "full": "EEEE, MMMM d, yyyy"},
+		months: [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		short:  [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		days:   [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	},
+	"it": {
+		styles: map[string]string{"short": "dd/MM/yy", "medium": "d MMM yyyy", "long": "d MMMM yyyy", "full": "EEEE d MMMM yyyy"},
+		months: [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		short:  [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		days:   [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+	},
+	"de": {
+		styles: map[string]string{"short": "dd.MM.yy", "medium": "dd.MM.yyyy", "long": "d. MMMM yyyy", "full": "EEEE, d. MMMM yyyy"},
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		short:  [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		days:   [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	},
+	"fr": {
+		styles: map[string]string{"short": "dd/MM/yyyy", "medium": "d MMM yyyy", "long": "d MMMM yyyy", "full": "EEEE d MMMM yyyy"},
+		months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		short:  [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:   [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+	},
+	"es": {
+		styles: map[string]string{"short": "d/M/yy", "medium": "d MMM yyyy", "long": "d 'de' MMMM 'de' yyyy", "full": "EEEE, d 'de' MMMM 'de' yyyy"},
+		months: [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		short:  [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:   [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+	},
+}
+
+// localeFilters returns the filters formatting values for locale, an empty locale meaning
+// English: `date` takes an optional style (short, medium, long or full), `number` optional
+// fixed decimals and `currency` an ISO 4217 code.
+func localeFilters(locale string) map[string]any {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.English
+	}
+	base, _ := tag.Base()
+	format, ok := dateFormats[base.String()]
+	if !ok {
+		format = dateFormats["en"]
+	}
+	printer := message.NewPrinter(tag)
+
+	return map[string]any{
+		"date": func(args ...any) (string, error) {
+			style, value, err := filterArgs("date", args)
+			if err != nil {
+				return "", err
+			}
+			t, err := toTime(value)
+			if err != nil {
+				return "", err
+			}
+			if style == "" {
+				style = "medium"
+			}
+			pattern, ok := format.styles[style]
+			if !ok {
+				return "", fmt.Errorf("date: unknown style %q", style)
+			}
+			return format.format(pattern, t), nil
+		},
+		"number": func(args ...any) (string, error) {
+			decimals, value, err := filterArgs("number", args)
+			if err != nil {
+				return "", err
+			}
+			n, err := toNumber(value)
+			if err != nil {
+				return "", err
+			}
+			if decimals == "" {
+				return printer.Sprint(textnumber.Decimal(n)), nil
+			}
+			scale, err := strconv.Atoi(decimals)
+			if err != nil || scale < 0 {
+				return "", fmt.Errorf("number: invalid decimals %q", decimals)
+			}
+			return printer.Sprint(textnumber.Decimal(n, textnumber.Scale(scale))), nil
+		},
+		"currency": func(args ...any) (string, error) {
+			code, value, err := filterArgs("currency", args)
+			if err != nil {
+				return "", err
+			}
+			unit, err := currency.ParseISO(code)
+			if err != nil {
+				return "", fmt.Errorf("currency: invalid code %q", code)
+			}
+			n, err := toNumber(value)
+			if err != nil {
+				return "", err
+			}
+			return printer.Sprint(currency.Symbol(unit.Amount(n))), nil
+		},
+	}
+}
+
+// filterArgs splits the arguments of a filter into its optional single argument and the
+// piped value, which comes last.
+func filterArgs(name string, args []any) (string, any, error) {
+	switch len(args) {
+	case 1:
+		return "", args[0], nil
+	case 2:
+		return fmt.Sprint(args[0]), args[1], nil
+	default:
+		return "", nil, fmt.Errorf("%s: expected at most one argument", name)
+	}
+}
+
+func (f dateFormat) format(pattern string, t time.Time) string {
+	var b strings.Builder
+
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+
+		if c == '\'' {
+			end := strings.IndexByte(pattern[i+1:], '\'')
+			if end < 0 {
+				b.WriteString(pattern[i+1:])
+				break
+			}
+			b.WriteString(pattern[i+1 : i+1+end])
+			i += end + 2
+			continue
+		}
+
+		n := 1
+		for i+n < len(pattern) && pattern[i+n] == c {
+			n++
+		}
+
+		switch {
+		case c == 'd' && n == 1:
+			b.WriteString(strconv.Itoa(t.Day()))
+		case c == 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case c == 'M' && n == 1:
+			b.WriteString(strconv.Itoa(int(t.Month())))
+		case c == 'M' && n == 2:
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case c == 'M' && n == 3:
+			b.WriteString(f.short[t.Month()-1])
+		case c == 'M':
+			b.WriteString(f.months[t.Month()-1])
+		case c == 'y' && n == 2:
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case c == 'y':
+			b.WriteString(strconv.Itoa(t.Year()))
+		case c == 'E':
+			b.WriteString(f.days[t.Weekday()])
+		default:
+			b.WriteString(pattern[i : i+n])
+		}
+		i += n
+	}
+
+	return b.String()
+}
+
+// toTime reads dates given as time.Time, RFC 3339 timestamps or YYYY-MM-DD strings.
+func toTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.DateOnly, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date: %v is not a date", value)
+}
+
+func toNumber(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%v is not a number", value)
+}
@@ -0,0 +1,93 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// blocks are the elements that break lines in the text of an HTML document.
+var blocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"div": true, "dl": true, "dt": true, "dd": true, "fieldset": true, "figcaption": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// HTMLText returns the visible text of an HTML document, with a line break per block.
+// Scripts, styles and the head are skipped.
+func HTMLText(source string) string {
+	var (
+		b       strings.Builder
+		skipped int
+	)
+
+	tokenizer := html.NewTokenizer(strings.NewReader(source))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+
+		case html.TextToken:
+			if skipped > 0 {
+				continue
+			}
+
+			text := string(tokenizer.Text())
+			words := strings.Fields(text)
+			if len(words) == 0 || startsWithSpace(text) {
+				space(&b)
+			}
+			if len(words) > 0 {
+				b.WriteString(strings.Join(words, " "))
+				if strings.TrimRightFunc(text, unicode.IsSpace) != text {
+					space(&b)
+				}
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			switch tag := string(name); {
+			case tag == "script" || tag == "style" || tag == "head":
+				skipped++
+			case blocks[tag]:
+				newline(&b)
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch tag := string(name); {
+			case tag == "script" || tag == "style" || tag == "head":
+				skipped = max(0, skipped-1)
+			case blocks[tag]:
+				newline(&b)
+			}
+		}
+	}
+}
+
+func startsWithSpace(text string) bool {
+	return strings.TrimLeftFunc(text, unicode.IsSpace) != text
+}
+
+// space separates words, unless the current line is empty or already ends with a space.
+func space(b *strings.Builder) {
+	text := b.String()
+	if text != "" && !strings.HasSuffix(text, " ") && !strings.HasSuffix(text, "\n") {
+		b.WriteString(" ")
+	}
+}
+
+// newline ends the current line, trimming its trailing space, unless it is empty.
+func newline(b *strings.Builder) {
+	text := strings.TrimRight(b.String(), " ")
+	if text == "" || strings.HasSuffix(text, "\n") {
+		return
+	}
+
+	b.Reset()
+	b.WriteString(text)
+	b.WriteString("\n")
+}
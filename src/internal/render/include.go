@@ -0,0 +1,64 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrTooLarge reports an expansion growing past its size limit.
+var ErrTooLarge = errors.New("expanded template is too large")
+
+// include matches the include directives `{{> name }}` and `{{ include "name" }}`, where name
+// is the ID or the name of another stored template.
+var include = regexp.MustCompile(`\{\{\s*(?:>\s*([^\s{}"]+)|include\s+"([^"{}]+)")\s*\}\}`)
+
+// HasIncludes reports whether body includes other templates.
+func HasIncludes(body string) bool {
+	return include.MatchString(body)
+}
+
+// ExpandIncludes replaces every include directive of body with the text that resolve returns
+// for its reference, stopping at the first error. The result is limited to maxSize bytes,
+// ErrTooLarge being returned as soon as it grows past them; 0 means no limit. A body without
+// includes is returned as it is.
+func ExpandIncludes(body string, maxSize int, resolve func(ref string) (string, error)) (string, error) {
+	var (
+		b    strings.Builder
+		last int
+	)
+
+	for _, m := range include.FindAllStringSubmatchIndex(body, -1) {
+		ref := ""
+		if m[2] >= 0 {
+			ref = body[m[2]:m[3]]
+		} else {
+			ref = strings.TrimSpace(body[m[4]:m[5]])
+		}
+
+		text, err := resolve(ref)
+		if err != nil {
+			return "", err
+		}
+
+		if maxSize > 0 && b.Len()+m[0]-last+len(text) > maxSize {
+			return "", fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxSize)
+		}
+
+		b.WriteString(body[last:m[0]])
+		b.WriteString(text)
+		last = m[1]
+	}
+
+	if last == 0 {
+		return body, nil
+	}
+
+	if maxSize > 0 && b.Len()+len(body)-last > maxSize {
+		return "", fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxSize)
+	}
+
+	b.WriteString(body[last:])
+	return b.String(), nil
+}
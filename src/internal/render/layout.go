@@ -0,0 +1,148 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// blockTag matches the block directives `{{ block "name" }}` and `{{ endblock }}`. A block of a
+// layout is replaced by the block of the same name of a template extending it, or else keeps
+// its own content.
+var blockTag = regexp.MustCompile(`\{\{\s*(?:block\s+"([^"{}]+)"|(endblock))\s*\}\}`)
+
+// segment is either literal text or a block.
+type segment struct {
+	text  string
+	block *block
+}
+
+type block struct {
+	name string
+	body []segment
+}
+
+// Blocks returns the content of every block of body, nested blocks included, by name. The
+// first block of a name wins.
+func Blocks(body string) (map[string]string, error) {
+	segments, err := parseBlocks(body)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := map[string]string{}
+	collectBlocks(segments, blocks)
+	return blocks, nil
+}
+
+// ApplyLayout replaces every block of layout with the override of the same name, when there
+// is one, and removes the block directives. Blocks nested in an override can be overridden
+// as well, except by the override itself.
+func ApplyLayout(layout string, overrides map[string]string) (string, error) {
+	segments, err := parseBlocks(layout)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := writeBlocks(&b, segments, overrides, map[string]bool{}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func parseBlocks(body string) ([]segment, error) {
+	var (
+		root  = &block{}
+		stack = []*block{root}
+		last  int
+	)
+
+	for _, m := range blockTag.FindAllStringSubmatchIndex(body, -1) {
+		current := stack[len(stack)-1]
+		if m[0] > last {
+			current.body = append(current.body, segment{text: body[last:m[0]]})
+		}
+		last = m[1]
+
+		if m[2] >= 0 {
+			child := &block{name: strings.TrimSpace(body[m[2]:m[3]])}
+			current.body = append(current.body, segment{block: child})
+			stack = append(stack, child)
+			continue
+		}
+
+		if len(stack) == 1 {
+			return nil, fmt.Errorf("endblock at offset %d closes no block", m[0])
+		}
+		stack = stack[:len(stack)-1]
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("block %q is not closed", stack[len(stack)-1].name)
+	}
+
+	if last < len(body) {
+		root.body = append(root.body, segment{text: body[last:]})
+	}
+	return root.body, nil
+}
+
+func collectBlocks(segments []segment, blocks map[string]string) {
+	for _, s := range segments {
+		if s.block == nil {
+			continue
+		}
+		if _, ok := blocks[s.block.name]; !ok {
+			var b strings.Builder
+			writeSource(&b, s.block.body)
+			blocks[s.block.name] = b.String()
+		}
+		collectBlocks(s.block.body, blocks)
+	}
+}
+
+// writeSource writes segments back with their block directives.
+func writeSource(b *strings.Builder, segments []segment) {
+	for _, s := range segments {
+		if s.block == nil {
+			b.WriteString(s.text)
+			continue
+		}
+		fmt.Fprintf(b, "{{ block %q }}", s.block.name)
+		writeSource(b, s.block.body)
+		b.WriteString("{{ endblock }}")
+	}
+}
+
+// writeBlocks writes segments with their blocks resolved, where active lists the overrides
+// being written so that a block does not override itself.
+func writeBlocks(b *strings.Builder, segments []segment, overrides map[string]string, active map[string]bool) error {
+	for _, s := range segments {
+		if s.block == nil {
+			b.WriteString(s.text)
+			continue
+		}
+
+		override, ok := overrides[s.block.name]
+		if !ok || active[s.block.name] {
+			if err := writeBlocks(b, s.block.body, overrides, active); err != nil {
+				return err
+			}
+			continue
+		}
+
+		nested, err := parseBlocks(override)
+		if err != nil {
+			return fmt.Errorf("block %q: %w", s.block.name, err)
+		}
+
+		active[s.block.name] = true
+		err = writeBlocks(b, nested, overrides, active)
+		delete(active, s.block.name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyLayout(t *testing.T) {
+	layout := `<html>{{ block "head" }}<title>Default</title>{{ endblock }}<body>{{ block "content" }}<p>Empty</p>{{ endblock }}</body></html>`
+
+	for _, tc := range []struct {
+		name      string
+		layout    string
+		overrides map[string]string
+		want      string
+	}{
+		{"no override", layout, nil, `<html><title>Default</title><body><p>Empty</p></body></html>`},
+		{"override", layout, map[string]string{"content": `<p>{{ name }}</p>`}, `<html><title>Default</title><body><p>{{ name }}</p></body></html>`},
+		{"unknown override", layout, map[string]string{"footer": `<p>x</p>`}, `<html><title>Default</title><body><p>Empty</p></body></html>`},
+		{
+			"nested block kept",
+			`{{ block "page" }}<main>{{ block "content" }}default{{ endblock }}</main>{{ endblock }}`,
+			map[string]string{"content": "mine"},
+			`<main>mine</main>`,
+		},
+		{
+			"nested block replaced with its parent",
+			`{{ block "page" }}<main>{{ block "content" }}default{{ endblock }}</main>{{ endblock }}`,
+			map[string]string{"page": "<div>{{ block \"content\" }}x{{ endblock }}</div>", "content": "mine"},
+			`<div>mine</div>`,
+		},
+		{
+			"override reusing its own name",
+			`{{ block "content" }}default{{ endblock }}`,
+			map[string]string{"content": `<p>{{ block "content" }}inner{{ endblock }}</p>`},
+			`<p>inner</p>`,
+		},
+	} {
+		got, err := ApplyLayout(tc.layout, tc.overrides)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	blocks, err := Blocks(`{{ block "page" }}<main>{{ block "content" }}mine{{ endblock }}</main>{{ endblock }}{{ block "content" }}second{{ endblock }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks["page"] != `<main>{{ block "content" }}mine{{ endblock }}</main>` {
+		t.Errorf("page = %q", blocks["page"])
+	}
+	if blocks["content"] != "mine" {
+		t.Errorf("content = %q, want the first block of the name", blocks["content"])
+	}
+}
+
+func TestLayoutErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		body string
+		want string
+	}{
+		{"unclosed block", `{{ block "content" }}<p>x</p>`, `block "content" is not closed`},
+		{"unclosed nested block", `{{ block "page" }}{{ block "content" }}x{{ endblock }}`, `block "page" is not closed`},
+		{"stray endblock", `<p>x</p>{{ endblock }}`, "endblock at offset 8 closes no block"},
+		{"extra endblock", `{{ block "content" }}x{{ endblock }}{{ endblock }}`, "closes no block"},
+	} {
+		if _, err := Blocks(tc.body); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Blocks err = %v, want %q", tc.name, err, tc.want)
+		}
+		if _, err := ApplyLayout(tc.body, nil); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: ApplyLayout err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	_, err := ApplyLayout(`{{ block "content" }}x{{ endblock }}`, map[string]string{"content": `{{ block "inner" }}`})
+	if err == nil || !strings.Contains(err.Error(), `block "content"`) {
+		t.Errorf("unclosed block in an override: err = %v", err)
+	}
+}
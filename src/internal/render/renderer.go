@@ -0,0 +1,248 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+)
+
+const (
+	leftDelim  = "\x02"
+	rightDelim = "\x03"
+)
+
+var (
+	placeholder = regexp.MustCompile(`\{\{([^{}]*)\}\}`)
+	identifier  = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	number      = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+)
+
+// Renderer substitutes `{{ name }}` placeholders with values. Placeholders may pipe the
+// value through filters, e.g. `{{ name | upper }}`; `{{ name | raw }}` marks a trusted
+// value that is written to HTML output without escaping. The date, number and currency
+// filters format values for the locale of the render.
+type Renderer interface {
+	Render(contentType model.ContentType, locale string, body string, values map[string]any) (string, error)
+}
+
+type renderer struct {
+	filters map[string]any
+}
+
+// NewRenderer builds a renderer with the built-in filters plus the given ones. Filters
+// receive their arguments first and the piped value last.
+func NewRenderer(filters map[string]any) Renderer {
+	all := map[string]any{
+		"upper": func(v any) string { return strings.ToUpper(fmt.Sprint(v)) },
+		"lower": func(v any) string { return strings.ToLower(fmt.Sprint(v)) },
+	}
+	for name, fn := range localeFilters("") {
+		all[name] = fn
+	}
+	for name, fn := range filters {
+		all[name] = fn
+	}
+
+	return &renderer{filters: all}
+}
+
+func (r *renderer) Render(contentType model.ContentType, locale string, body string, values map[string]any) (string, error) {
+	source, names, err := r.translate(body)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	for name := range names {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", fmt.Errorf("missing values for variables: %s", strings.Join(missing, ", "))
+	}
+
+	var out bytes.Buffer
+
+	switch contentType {
+	case model.HTML:
+		funcs := htmltemplate.FuncMap{
+			"raw": func(v any) htmltemplate.HTML { return htmltemplate.HTML(fmt.Sprint(v)) },
+		}
+		for name, fn := range r.filters {
+			funcs[name] = fn
+		}
+		for name, fn := range localeFilters(locale) {
+			funcs[name] = fn
+		}
+
+		tpl, err := htmltemplate.New("document").Delims(leftDelim, rightDelim).Funcs(funcs).Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse template: %w", err)
+		}
+
+		if err := tpl.Execute(&out, values); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+
+	case model.PLAIN_TEXT:
+		funcs := texttemplate.FuncMap{
+			"raw": func(v any) string { return fmt.Sprint(v) },
+		}
+		for name, fn := range r.filters {
+			funcs[name] = fn
+		}
+		for name, fn := range localeFilters(locale) {
+			funcs[name] = fn
+		}
+
+		tpl, err := texttemplate.New("document").Delims(leftDelim, rightDelim).Funcs(funcs).Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse template: %w", err)
+		}
+
+		if err := tpl.Execute(&out, values); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+
+	default:
+		return "", fmt.Errorf("rendering is not supported for content type: %s", contentType)
+	}
+
+	return out.String(), nil
+}
+
+// translate rewrites placeholders into Go template actions. Anything that is not a valid
+// placeholder is kept as literal text, matching what variable extraction reports.
+func (r *renderer) translate(body string) (string, map[string]struct{}, error) {
+	if strings.ContainsAny(body, leftDelim+rightDelim) {
+		return "", nil, fmt.Errorf("template contains reserved control characters")
+	}
+
+	names := make(map[string]struct{})
+	var source strings.Builder
+	last := 0
+
+	for _, loc := range placeholder.FindAllStringSubmatchIndex(body, -1) {
+		action, name, ok, err := r.action(body[loc[2]:loc[3]])
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		source.WriteString(body[last:loc[0]])
+		source.WriteString(action)
+		names[name] = struct{}{}
+		last = loc[1]
+	}
+	source.WriteString(body[last:])
+
+	return source.String(), names, nil
+}
+
+func (r *renderer) action(inner string) (string, string, bool, error) {
+	segments, err := splitPipeline(inner)
+	if err != nil {
+		return "", "", false, nil
+	}
+
+	name := strings.TrimSpace(segments[0])
+	if !identifier.MatchString(name) {
+		return "", "", false, nil
+	}
+
+	var action strings.Builder
+	action.WriteString(leftDelim + "index . " + strconv.Quote(name))
+
+	for _, segment := range segments[1:] {
+		fields, err := splitFields(segment)
+		if err != nil || len(fields) == 0 {
+			return "", "", false, fmt.Errorf("invalid filter in placeholder {{%s}}", inner)
+		}
+
+		if _, ok := r.filters[fields[0]]; !ok && fields[0] != "raw" {
+			return "", "", false, fmt.Errorf("unknown filter %q in placeholder {{%s}}", fields[0], inner)
+		}
+
+		action.WriteString(" | " + fields[0])
+		for _, arg := range fields[1:] {
+			action.WriteString(" " + arg)
+		}
+	}
+
+	action.WriteString(rightDelim)
+	return action.String(), name, true, nil
+}
+
+// splitPipeline splits on `|` outside of double quoted strings.
+func splitPipeline(inner string) ([]string, error) {
+	var (
+		segments []string
+		current  strings.Builder
+		quoted   bool
+	)
+
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(inner):
+			current.WriteByte(c)
+			i++
+			current.WriteByte(inner[i])
+			continue
+		case c == '"':
+			quoted = !quoted
+		case c == '|' && !quoted:
+			segments = append(segments, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated string")
+	}
+
+	return append(segments, current.String()), nil
+}
+
+// splitFields splits a filter segment into its name and literal arguments.
+func splitFields(segment string) ([]string, error) {
+	var fields []string
+	rest := strings.TrimSpace(segment)
+
+	for rest != "" {
+		if rest[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, quoted)
+			rest = strings.TrimSpace(rest[len(quoted):])
+			continue
+		}
+
+		field, tail, _ := strings.Cut(rest, " ")
+		if len(fields) == 0 && !identifier.MatchString(field) {
+			return nil, fmt.Errorf("invalid filter name %q", field)
+		}
+		if len(fields) > 0 && !number.MatchString(field) {
+			return nil, fmt.Errorf("invalid filter argument %q", field)
+		}
+		fields = append(fields, field)
+		rest = strings.TrimSpace(tail)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+)
+
+func TestRenderEscaping(t *testing.T) {
+	r := NewRenderer(nil)
+	script := `<script>alert("x")</script>`
+
+	for _, tc := range []struct {
+		name        string
+		contentType model.ContentType
+		body        string
+		want        string
+	}{
+		{"html text", model.HTML, `<p>{{ name }}</p>`, `<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>`},
+		{"html attribute", model.HTML, `<a title="{{ name }}">x</a>`, `<a title="&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;">x</a>`},
+		{"html filtered", model.HTML, `<p>{{ name | upper }}</p>`, `<p>&lt;SCRIPT&gt;ALERT(&#34;X&#34;)&lt;/SCRIPT&gt;</p>`},
+		{"html raw", model.HTML, `<p>{{ name | raw }}</p>`, `<p>` + script + `</p>`},
+		{"plain text", model.PLAIN_TEXT, `Hello {{ name }}`, `Hello ` + script},
+		{"plain text raw", model.PLAIN_TEXT, `Hello {{ name | raw }}`, `Hello ` + script},
+	} {
+		got, err := r.Render(tc.contentType, "", tc.body, map[string]any{"name": script})
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRenderEscapesURLs(t *testing.T) {
+	got, err := NewRenderer(nil).Render(model.HTML, "", `<a href="{{ url }}">x</a>`, map[string]any{"url": "javascript:alert(1)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "javascript:") {
+		t.Fatalf("unsafe URL rendered: %q", got)
+	}
+}
+
+func TestRenderMissingValues(t *testing.T) {
+	_, err := NewRenderer(nil).Render(model.HTML, "", `{{ b }} {{ a }} {{ b }}`, map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "a, b") {
+		t.Fatalf("err = %v, want the missing variables listed", err)
+	}
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Sanitizer strips HTML that is not in the configured allowlist.
+type Sanitizer interface {
+	Sanitize(html string) string
+}
+
+type sanitizer struct {
+	policy *bluemonday.Policy
+}
+
+// NewSanitizer starts from the named base policy (ugc, strict or none) and allows the
+// extra tags and attributes on top. Attributes are written as `name` to allow them on
+// every tag or `name:tag|tag` to scope them.
+func NewSanitizer(base string, tags, attributes []string) (Sanitizer, error) {
+	var policy *bluemonday.Policy
+
+	switch base {
+	case "ugc":
+		policy = bluemonday.UGCPolicy()
+	case "strict":
+		policy = bluemonday.StrictPolicy()
+	case "none":
+		policy = bluemonday.NewPolicy()
+		policy.AllowStandardURLs()
+	default:
+		return nil, fmt.Errorf("unsupported html policy: %s (must be ugc, strict or none)", base)
+	}
+
+	if len(tags) > 0 {
+		policy.AllowElements(tags...)
+	}
+
+	for _, attribute := range attributes {
+		name, scope, scoped := strings.Cut(attribute, ":")
+		if !scoped {
+			policy.AllowAttrs(name).Globally()
+			continue
+		}
+		policy.AllowAttrs(name).OnElements(strings.Split(scope, "|")...)
+	}
+
+	return &sanitizer{policy: policy}, nil
+}
+
+// Sanitize protects placeholders while sanitising, so that placeholders used as
+// attribute values survive URL and attribute checks and are escaped at render time. Only
+// placeholders the renderer understands are protected; anything else between braces is
+// sanitised like the rest of the document.
+func (s *sanitizer) Sanitize(html string) string {
+	nonce := make([]byte, 6)
+	_, _ = rand.Read(nonce)
+	prefix := "tpl" + hex.EncodeToString(nonce)
+
+	var placeholders []string
+	protected := placeholder.ReplaceAllStringFunc(html, func(match string) string {
+		if !shielded(match) {
+			return match
+		}
+		placeholders = append(placeholders, match)
+		return fmt.Sprintf("%s%dx", prefix, len(placeholders)-1)
+	})
+
+	sanitized := s.policy.Sanitize(protected)
+
+	for i, original := range placeholders {
+		sanitized = strings.ReplaceAll(sanitized, fmt.Sprintf("%s%dx", prefix, i), original)
+	}
+
+	return sanitized
+}
+
+// shielded reports whether match is a variable placeholder, include or block tag free of
+// markup, as only those are rewritten at render time.
+func shielded(match string) bool {
+	inner := strings.TrimSpace(match[2 : len(match)-2])
+	if include.MatchString(match) || blockTag.MatchString(match) {
+		inner = strings.TrimPrefix(inner, ">")
+	}
+	if strings.ContainsAny(inner, "<>&'") {
+		return false
+	}
+	if include.MatchString(match) || blockTag.MatchString(match) {
+		return true
+	}
+
+	segments, err := splitPipeline(inner)
+	if err != nil || !identifier.MatchString(strings.TrimSpace(segments[0])) {
+		return false
+	}
+	for _, segment := range segments[1:] {
+		if fields, err := splitFields(segment); err != nil || len(fields) == 0 {
+			return false
+		}
+	}
+	return true
+}
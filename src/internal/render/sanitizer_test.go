@@ -0,0 +1,54 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+)
+
+func TestSanitizeKeepsPlaceholders(t *testing.T) {
+	s, err := NewSanitizer("ugc", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{
+		`<p>{{ name }}</p>`,
+		`<img src="{{ url }}" alt="{{ alt }}">`,
+		`<p>{{ total | currency "EUR" }}</p>`,
+		`<p>{{ title | truncate 20 | upper }}</p>`,
+		`{{> footer }}`,
+		`{{ include "header" }}`,
+		`{{ block "content" }}<p>x</p>{{ endblock }}`,
+	} {
+		if got := s.Sanitize(body); got != body {
+			t.Errorf("Sanitize(%q) = %q", body, got)
+		}
+	}
+}
+
+func TestSanitizeStripsMarkupInBraces(t *testing.T) {
+	s, err := NewSanitizer("ugc", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{
+		`{{<img src=x onerror=alert(1)>}}`,
+		`{{ name | upper "<img src=x onerror=alert(1)>" }}`,
+		`{{> <img src=x onerror=alert(1)> }}`,
+	} {
+		if got := s.Sanitize(body); strings.Contains(got, "onerror") {
+			t.Errorf("Sanitize(%q) = %q, kept the payload", body, got)
+		}
+	}
+
+	out, err := NewRenderer(nil).Render(model.HTML, "", s.Sanitize(`{{<img src=x onerror=alert(1)>}}`), map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "onerror") {
+		t.Errorf("rendered %q", out)
+	}
+}
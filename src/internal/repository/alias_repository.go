@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AliasRepository interface {
+	FindOne(ctx context.Context, name string) (*model.Alias, error)
+
+	// Save creates the alias or re-points the existing one of the same name.
+	Save(ctx context.Context, m *model.Alias) (*model.Alias, error)
+	DeleteOne(ctx context.Context, name string) error
+
+	// EnsureIndexes keeps alias names unique within a tenant.
+	EnsureIndexes(ctx context.Context) error
+}
+
+type aliasRepository struct {
+	repo *CRUDRepository[model.Alias]
+}
+
+// NewAliasRepository stores the aliases of every tenant in a single collection, scoping
+// queries to the tenant found in ctx.
+func NewAliasRepository(collection *mongo.Collection) AliasRepository {
+	return &aliasRepository{repo: NewRepository[model.Alias](collection)}
+}
+
+func (r *aliasRepository) FindOne(ctx context.Context, name string) (*model.Alias, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.repo.FindOne(ctx, bson.M{"name": name, "tenant": tenantID})
+}
+
+func (r *aliasRepository) Save(ctx context.Context, m *model.Alias) (*model.Alias, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Tenant = tenantID
+	return r.repo.Upsert(ctx, bson.M{"name": m.Name, "tenant": tenantID}, m)
+}
+
+func (r *aliasRepository) DeleteOne(ctx context.Context, name string) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.repo.Delete(ctx, bson.M{"name": name, "tenant": tenantID})
+}
+
+func (r *aliasRepository) EnsureIndexes(ctx context.Context) error {
+	return r.repo.EnsureIndexes(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetName("tenant_name").SetUnique(true),
+	})
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/clients/cache"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type cachedDocumentRepository struct {
+	next  DocumentRepository
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// NewCachedDocumentRepository serves FindOne from cache and drops the cached record
+// whenever the document is updated or deleted.
+func NewCachedDocumentRepository(next DocumentRepository, cache cache.Cache, ttl time.Duration) DocumentRepository {
+	return &cachedDocumentRepository{
+		next:  next,
+		cache: cache,
+		ttl:   ttl,
+	}
+}
+
+func (r *cachedDocumentRepository) FindOne(ctx context.Context, ID primitive.ObjectID) (*model.Document, error) {
+	key := DocumentCacheKey(ctx, ID)
+
+	if cached, ok, err := r.cache.Get(ctx, key); err != nil {
+		logger.WarnContext(ctx, "cachedDocumentRepository.FindOne", "status", "cache failure", "error", err)
+	} else if ok {
+		var doc model.Document
+		if err := bson.Unmarshal(cached, &doc); err == nil {
+			return &doc, nil
+		}
+	}
+
+	doc, err := r.next.FindOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(ctx, key, doc)
+	return doc, nil
+}
+
+func (r *cachedDocumentRepository) FindByName(ctx context.Context, name string) (*model.Document, error) {
+	return r.next.FindByName(ctx, name)
+}
+
+func (r *cachedDocumentRepository) FindBySlug(ctx context.Context, slug string) (*model.Document, error) {
+	return r.next.FindBySlug(ctx, slug)
+}
+
+func (r *cachedDocumentRepository) InsertOne(ctx context.Context, m *model.Document) (*model.Document, error) {
+	return r.next.InsertOne(ctx, m)
+}
+
+func (r *cachedDocumentRepository) UpdateOne(ctx context.Context, m *model.Document) (*model.Document, error) {
+	r.invalidate(ctx, m.ID)
+
+	updated, err := r.next.UpdateOne(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx, m.ID)
+	return updated, nil
+}
+
+func (r *cachedDocumentRepository) DeleteOne(ctx context.Context, ID primitive.ObjectID) error {
+	r.invalidate(ctx, ID)
+
+	if err := r.next.DeleteOne(ctx, ID); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, ID)
+	return nil
+}
+
+func (r *cachedDocumentRepository) UpdateVariables(ctx context.Context, ID primitive.ObjectID, variables []string, content string, parserVersion int) error {
+	r.invalidate(ctx, ID)
+
+	if err := r.next.UpdateVariables(ctx, ID, variables, content, parserVersion); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, ID)
+	return nil
+}
+
+func (r *cachedDocumentRepository) FindOutdated(ctx context.Context, parserVersion int, after primitive.ObjectID, limit int) ([]model.Document, error) {
+	return r.next.FindOutdated(ctx, parserVersion, after, limit)
+}
+
+func (r *cachedDocumentRepository) List(ctx context.Context, filter model.DocumentFilter) ([]model.Document, error) {
+	return r.next.List(ctx, filter)
+}
+
+func (r *cachedDocumentRepository) Search(ctx context.Context, text string, filter model.DocumentFilter) ([]model.SearchHit, error) {
+	return r.next.Search(ctx, text, filter)
+}
+
+func (r *cachedDocumentRepository) EnsureIndexes(ctx context.Context) error {
+	return r.next.EnsureIndexes(ctx)
+}
+
+func (r *cachedDocumentRepository) store(ctx context.Context, key string, doc *model.Document) {
+	encoded, err := bson.Marshal(doc)
+	if err != nil {
+		return
+	}
+
+	if err := r.cache.Set(ctx, key, encoded, r.ttl); err != nil {
+		logger.WarnContext(ctx, "cachedDocumentRepository.store", "status", "cache failure", "error", err)
+	}
+}
+
+// invalidate runs before and after writes so that a concurrent read cannot leave a stale
+// record cached once the write has completed.
+func (r *cachedDocumentRepository) invalidate(ctx context.Context, ID primitive.ObjectID) {
+	if err := r.cache.Delete(ctx, DocumentCacheKey(ctx, ID)); err != nil {
+		logger.WarnContext(ctx, "cachedDocumentRepository.invalidate", "status", "cache failure", "error", err)
+	}
+}
+
+func DocumentCacheKey(ctx context.Context, ID primitive.ObjectID) string {
+	return "document:" + tenant.Key(ctx) + ":" + ID.Hex()
+}
@@ -1,34 +1,334 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"sort"
 
 	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type DocumentRepository interface {
 	FindOne(ctx context.Context, ID primitive.ObjectID) (*model.Document, error)
+
+	// FindByName returns the only document of the tenant with the given name, failing when
+	// there are none or several.
+	FindByName(ctx context.Context, name string) (*model.Document, error)
+
+	// FindBySlug returns the document of the tenant with the given slug.
+	FindBySlug(ctx context.Context, slug string) (*model.Document, error)
+
 	InsertOne(ctx context.Context, m *model.Document) (*model.Document, error)
+	UpdateOne(ctx context.Context, m *model.Document) (*model.Document, error)
+	DeleteOne(ctx context.Context, ID primitive.ObjectID) error
+
+	// UpdateVariables stores the variables extracted from the default body of a template,
+	// and the text extracted from its file, if any.
+	UpdateVariables(ctx context.Context, ID primitive.ObjectID, variables []string, content string, parserVersion int) error
+
+	// FindOutdated lists, in ID order, up to limit templates of every tenant with an ID
+	// above after that were extracted with a parser older than parserVersion. It is meant
+	// for maintenance jobs, which page through with the ID of the last template, and
+	// ignores the tenant in ctx.
+	FindOutdated(ctx context.Context, parserVersion int, after primitive.ObjectID, limit int) ([]model.Document, error)
+
+	// List returns the documents of the tenant matching filter in ID order, without their
+	// bodies.
+	List(ctx context.Context, filter model.DocumentFilter) ([]model.Document, error)
+
+	// Search returns the documents of the tenant matching the text search and filter, most
+	// relevant first, with their localized variants left out.
+	Search(ctx context.Context, text string, filter model.DocumentFilter) ([]model.SearchHit, error)
+
+	// EnsureIndexes creates the indexes of the shared and the tenant collections.
+	EnsureIndexes(ctx context.Context) error
+}
+
+// documentIndexes keeps slugs unique within a tenant, documents without one not being indexed,
+// and serves the filters of List.
+var documentIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "slug", Value: 1}},
+		Options: options.Index().
+			SetName("tenant_slug").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"slug": bson.M{"$type": "string"}}),
+	},
+	{
+		Keys:    bson.D{{Key: "tenant", Value: 1}, {Key: "tags", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("tenant_tags"),
+	},
+	{
+		Keys:    bson.D{{Key: "tenant", Value: 1}, {Key: "category", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("tenant_category"),
+	},
+	{
+		Keys:    bson.D{{Key: "tenant", Value: 1}, {Key: "state", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("tenant_state"),
+	},
+	{
+		Keys:    bson.D{{Key: "metadata.$**", Value: 1}},
+		Options: options.Index().SetName("metadata"),
+	},
+	{
+		// Templates are written in many languages, so words are matched without stemming
+		// or stop words.
+		Keys: bson.D{
+			{Key: "tenant", Value: 1},
+			{Key: "name", Value: "text"},
+			{Key: "summary", Value: "text"},
+			{Key: "body.text", Value: "text"},
+			{Key: "body.content", Value: "text"},
+		},
+		Options: options.Index().
+			SetName("tenant_text").
+			SetDefaultLanguage("none").
+			SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "summary", Value: 5}, {Key: "body.text", Value: 1}, {Key: "body.content", Value: 1}}),
+	},
 }
 
 type documentRepository struct {
 	repo       *CRUDRepository[model.Document]
 	collection *mongo.Collection
+	tenants    map[string]*CRUDRepository[model.Document]
 }
 
-func NewDocumentRepository(collection *mongo.Collection) DocumentRepository {
+// NewDocumentRepository scopes every query to the tenant found in ctx. Tenants listed in
+// tenantCollections are served from their own collection instead of the shared one.
+func NewDocumentRepository(collection *mongo.Collection, tenantCollections map[string]*mongo.Collection) DocumentRepository {
+	tenants := make(map[string]*CRUDRepository[model.Document], len(tenantCollections))
+	for t, c := range tenantCollections {
+		tenants[t] = NewRepository[model.Document](c)
+	}
+
 	return &documentRepository{
 		repo:       NewRepository[model.Document](collection),
 		collection: collection,
+		tenants:    tenants,
 	}
 }
 
 func (r *documentRepository) FindOne(ctx context.Context, ID primitive.ObjectID) (*model.Document, error) {
-	return r.repo.Find(ctx, ID)
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.crud(tenantID).FindOne(ctx, bson.M{"_id": ID, "tenant": tenantID})
+}
+
+func (r *documentRepository) FindByName(ctx context.Context, name string) (*model.Document, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := r.crud(tenantID).FindAll(ctx, bson.M{"name": name, "tenant": tenantID})
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(docs) {
+	case 0:
+		return nil, ErrNotFound
+	case 1:
+		return &docs[0], nil
+	default:
+		return nil, fmt.Errorf("%d documents are named %q", len(docs), name)
+	}
+}
+
+func (r *documentRepository) FindBySlug(ctx context.Context, slug string) (*model.Document, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.crud(tenantID).FindOne(ctx, bson.M{"slug": slug, "tenant": tenantID})
 }
 
 func (r *documentRepository) InsertOne(ctx context.Context, m *model.Document) (*model.Document, error) {
-	return r.repo.Insert(ctx, m)
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Tenant = tenantID
+	return r.crud(tenantID).Insert(ctx, m)
+}
+
+func (r *documentRepository) UpdateOne(ctx context.Context, m *model.Document) (*model.Document, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Tenant = tenantID
+	return r.crud(tenantID).Replace(ctx, bson.M{"_id": m.ID, "tenant": tenantID}, m)
+}
+
+func (r *documentRepository) DeleteOne(ctx context.Context, ID primitive.ObjectID) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.crud(tenantID).Delete(ctx, bson.M{"_id": ID, "tenant": tenantID})
+}
+
+func (r *documentRepository) UpdateVariables(ctx context.Context, ID primitive.ObjectID, variables []string, content string, parserVersion int) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"body.variables": variables, "body.parserVersion": parserVersion}}
+	if content != "" {
+		update["$set"].(bson.M)["body.content"] = content
+	} else {
+		update["$unset"] = bson.M{"body.content": ""}
+	}
+
+	return r.crud(tenantID).Update(ctx, bson.M{"_id": ID, "tenant": tenantID}, update)
+}
+
+func (r *documentRepository) FindOutdated(ctx context.Context, parserVersion int, after primitive.ObjectID, limit int) ([]model.Document, error) {
+	filter := bson.M{
+		"type":   model.TEMPLATE,
+		"source": bson.M{"$in": bson.A{model.TEXT, model.FILE}},
+		"$or": bson.A{
+			bson.M{"body.parserVersion": bson.M{"$exists": false}},
+			bson.M{"body.parserVersion": bson.M{"$lt": parserVersion}},
+		},
+	}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	// Each collection returns its first limit matches, so the first limit of their union
+	// are the first limit matches overall.
+	result, err := r.repo.FindMany(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repo := range r.tenants {
+		docs, err := repo.FindMany(ctx, filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, docs...)
+	}
+
+	if len(r.tenants) > 0 {
+		sort.Slice(result, func(i, j int) bool {
+			return bytes.Compare(result[i].ID[:], result[j].ID[:]) < 0
+		})
+	}
+
+	return result[:min(len(result), limit)], nil
+}
+
+func (r *documentRepository) List(ctx context.Context, filter model.DocumentFilter) ([]model.Document, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := listQuery(tenantID, filter)
+	if !filter.After.IsZero() {
+		query["_id"] = bson.M{"$gt": filter.After}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"body": 0, "locales": 0})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	return r.crud(tenantID).FindMany(ctx, query, opts)
+}
+
+func (r *documentRepository) Search(ctx context.Context, text string, filter model.DocumentFilter) ([]model.SearchHit, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := listQuery(tenantID, filter)
+	query["$text"] = bson.M{"$search": text}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"score": score, "locales": 0, "body.declared": 0})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	return findAs[model.SearchHit](ctx, r.crud(tenantID), "search", query, opts)
+}
+
+// listQuery builds the query of List and Search, without the cursor.
+func listQuery(tenantID string, filter model.DocumentFilter) bson.M {
+	query := bson.M{"tenant": tenantID}
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$all": filter.Tags}
+	}
+	if filter.Category != "" {
+		query["category"] = filter.Category
+	}
+	for key, value := range filter.Metadata {
+		query["metadata."+key] = value
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.Source != "" {
+		query["source"] = filter.Source
+	}
+	if filter.ContentType != "" {
+		query["contentType"] = filter.ContentType
+	}
+	switch filter.State {
+	case "":
+	case model.PUBLISHED:
+		// Documents stored before lifecycle states have none and are published.
+		query["state"] = bson.M{"$in": bson.A{model.PUBLISHED, nil}}
+	default:
+		query["state"] = filter.State
+	}
+	return query
+}
+
+func (r *documentRepository) EnsureIndexes(ctx context.Context) error {
+	if err := r.repo.EnsureIndexes(ctx, documentIndexes...); err != nil {
+		return err
+	}
+
+	for t, repo := range r.tenants {
+		if err := repo.EnsureIndexes(ctx, documentIndexes...); err != nil {
+			return fmt.Errorf("tenant %s: %w", t, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *documentRepository) crud(tenantID string) *CRUDRepository[model.Document] {
+	if repo, ok := r.tenants[tenantID]; ok {
+		return repo
+	}
+	return r.repo
 }
@@ -2,11 +2,26 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
+	"github.com/antoniofrisenda/template-service/src/internal/metrics"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var logger = logging.For("repository")
+
+var (
+	// ErrDuplicate is returned when a write breaks a unique index.
+	ErrDuplicate = errors.New("duplicate key")
+
+	// ErrNotFound is returned when no document matches.
+	ErrNotFound = errors.New("document not found")
 )
 
 type CRUDRepository[T any] struct {
@@ -18,11 +33,21 @@ func NewRepository[T any](m *mongo.Collection) *CRUDRepository[T] {
 }
 
 func (repo *CRUDRepository[T]) Find(ctx context.Context, ID primitive.ObjectID) (*T, error) {
+	return repo.FindOne(ctx, bson.M{"_id": ID})
+}
+
+func (repo *CRUDRepository[T]) FindOne(ctx context.Context, filter bson.M) (*T, error) {
+	start := time.Now()
+
 	var t T
-	if err := repo.collection.FindOne(ctx, bson.M{"_id": ID}).Decode(&t); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("document not found")
-		}
+	err := repo.collection.FindOne(ctx, filter).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		repo.observe(ctx, "find", start, nil)
+		return nil, ErrNotFound
+	}
+
+	repo.observe(ctx, "find", start, err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to find document: %w", err)
 	}
 	return &t, nil
@@ -33,10 +58,160 @@ func (repo *CRUDRepository[T]) Insert(ctx context.Context, t *T) (*T, error) {
 		return nil, fmt.Errorf("cannot insert nil document")
 	}
 
+	start := time.Now()
 	_, err := repo.collection.InsertOne(ctx, t)
+	repo.observe(ctx, "insert", start, err)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert document: %w", err)
 	}
 
 	return t, nil
 }
+
+func (repo *CRUDRepository[T]) FindAll(ctx context.Context, filter bson.M) ([]T, error) {
+	return repo.FindMany(ctx, filter, nil)
+}
+
+// FindMany lists the documents matching filter, sorted, limited and projected by opts.
+func (repo *CRUDRepository[T]) FindMany(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]T, error) {
+	return findAs[T](ctx, repo, "find_many", filter, opts)
+}
+
+// findAs lists the documents of repo matching filter decoded as R, for projections adding
+// computed fields such as text scores to T.
+func findAs[R, T any](ctx context.Context, repo *CRUDRepository[T], op string, filter bson.M, opts *options.FindOptions) ([]R, error) {
+	start := time.Now()
+
+	cursor, err := repo.collection.Find(ctx, filter, opts)
+	if err != nil {
+		repo.observe(ctx, op, start, err)
+		return nil, fmt.Errorf("failed to find documents: %w", err)
+	}
+
+	var result []R
+	err = cursor.All(ctx, &result)
+	repo.observe(ctx, op, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode documents: %w", err)
+	}
+
+	return result, nil
+}
+
+func (repo *CRUDRepository[T]) Update(ctx context.Context, filter bson.M, update bson.M) error {
+	start := time.Now()
+	result, err := repo.collection.UpdateOne(ctx, filter, update)
+	repo.observe(ctx, "update", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// FindOneAndUpdate atomically applies update to the first document matching filter in sort
+// order and returns it as updated. It returns nil, without error, when nothing matches.
+func (repo *CRUDRepository[T]) FindOneAndUpdate(ctx context.Context, filter bson.M, sort bson.D, update bson.M) (*T, error) {
+	start := time.Now()
+
+	opts := options.FindOneAndUpdate().SetSort(sort).SetReturnDocument(options.After)
+
+	var t T
+	err := repo.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		repo.observe(ctx, "find_and_update", start, nil)
+		return nil, nil
+	}
+
+	repo.observe(ctx, "find_and_update", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find and update document: %w", err)
+	}
+	return &t, nil
+}
+
+func (repo *CRUDRepository[T]) Replace(ctx context.Context, filter bson.M, t *T) (*T, error) {
+	if t == nil {
+		return nil, fmt.Errorf("cannot replace with nil document")
+	}
+
+	start := time.Now()
+	result, err := repo.collection.ReplaceOne(ctx, filter, t)
+	repo.observe(ctx, "replace", start, err)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace document: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, ErrNotFound
+	}
+
+	return t, nil
+}
+
+// Upsert replaces the document matching filter with t, inserting t when there is none.
+func (repo *CRUDRepository[T]) Upsert(ctx context.Context, filter bson.M, t *T) (*T, error) {
+	if t == nil {
+		return nil, fmt.Errorf("cannot upsert nil document")
+	}
+
+	start := time.Now()
+	_, err := repo.collection.ReplaceOne(ctx, filter, t, options.Replace().SetUpsert(true))
+	repo.observe(ctx, "upsert", start, err)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert document: %w", err)
+	}
+
+	return t, nil
+}
+
+// EnsureIndexes creates the given indexes, leaving those that already exist untouched.
+func (repo *CRUDRepository[T]) EnsureIndexes(ctx context.Context, indexes ...mongo.IndexModel) error {
+	start := time.Now()
+	_, err := repo.collection.Indexes().CreateMany(ctx, indexes)
+	repo.observe(ctx, "create_indexes", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+	return nil
+}
+
+func (repo *CRUDRepository[T]) Delete(ctx context.Context, filter bson.M) error {
+	start := time.Now()
+	result, err := repo.collection.DeleteOne(ctx, filter)
+	repo.observe(ctx, "delete", start, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *CRUDRepository[T]) observe(ctx context.Context, operation string, start time.Time, err error) {
+	metrics.MongoOperationDuration.
+		WithLabelValues(repo.collection.Name(), operation, metrics.Result(err)).
+		Observe(metrics.Since(start))
+
+	if err != nil {
+		logger.ErrorContext(ctx, "CRUDRepository."+operation, "collection", repo.collection.Name(), "status", "failure", "error", err, "duration", time.Since(start))
+		return
+	}
+	logger.DebugContext(ctx, "CRUDRepository."+operation, "collection", repo.collection.Name(), "status", "success", "duration", time.Since(start))
+}
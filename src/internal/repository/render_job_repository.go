@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RenderJobRepository interface {
+	FindOne(ctx context.Context, ID primitive.ObjectID) (*model.RenderJob, error)
+	InsertOne(ctx context.Context, m *model.RenderJob) (*model.RenderJob, error)
+
+	// Claim locks the oldest job waiting to run, of any tenant, until lockedUntil and returns
+	// it, or nil when there is none. Running jobs whose lock expired are claimed again.
+	Claim(ctx context.Context, lockedUntil time.Time) (*model.RenderJob, error)
+
+	// Save replaces a claimed job. It ignores the tenant in ctx, as workers serve every tenant.
+	Save(ctx context.Context, m *model.RenderJob) error
+}
+
+type renderJobRepository struct {
+	repo *CRUDRepository[model.RenderJob]
+}
+
+// NewRenderJobRepository stores the jobs of every tenant in a single collection, scoping
+// client queries to the tenant found in ctx.
+func NewRenderJobRepository(collection *mongo.Collection) RenderJobRepository {
+	return &renderJobRepository{repo: NewRepository[model.RenderJob](collection)}
+}
+
+func (r *renderJobRepository) FindOne(ctx context.Context, ID primitive.ObjectID) (*model.RenderJob, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.repo.FindOne(ctx, bson.M{"_id": ID, "tenant": tenantID})
+}
+
+func (r *renderJobRepository) InsertOne(ctx context.Context, m *model.RenderJob) (*model.RenderJob, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Tenant = tenantID
+	return r.repo.Insert(ctx, m)
+}
+
+func (r *renderJobRepository) Claim(ctx context.Context, lockedUntil time.Time) (*model.RenderJob, error) {
+	now := time.Now().UTC()
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": model.QUEUED},
+			bson.M{"status": model.RUNNING, "lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"status": model.RUNNING, "lockedUntil": lockedUntil.UTC(), "startedAt": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	return r.repo.FindOneAndUpdate(ctx, filter, bson.D{{Key: "createdAt", Value: 1}}, update)
+}
+
+func (r *renderJobRepository) Save(ctx context.Context, m *model.RenderJob) error {
+	_, err := r.repo.Replace(ctx, bson.M{"_id": m.ID, "tenant": m.Tenant}, m)
+	return err
+}
@@ -0,0 +1,152 @@
+package search
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// window is the number of bytes of text kept on each side of a match in a snippet.
+	window = 60
+
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+var (
+	phrase = regexp.MustCompile(`"([^"]*)"`)
+	space  = regexp.MustCompile(`\s+`)
+)
+
+// Terms returns the words and quoted phrases of a text search, lowercased, without the
+// excluded words prefixed with a minus.
+func Terms(query string) []string {
+	var terms []string
+	add := func(term string) {
+		term = strings.ToLower(strings.TrimSpace(space.ReplaceAllString(term, " ")))
+		if term != "" && !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+
+	for _, m := range phrase.FindAllStringSubmatch(query, -1) {
+		add(m[1])
+	}
+
+	for _, word := range strings.Fields(phrase.ReplaceAllString(query, " ")) {
+		if !strings.HasPrefix(word, "-") {
+			add(strings.Trim(word, `"`))
+		}
+	}
+	return terms
+}
+
+// Highlight returns up to limit snippets of text around the whole-word occurrences of terms,
+// in order, with the occurrences wrapped in <mark> tags. The text is HTML escaped, so that
+// snippets can be displayed as they are.
+func Highlight(text string, terms []string, limit int) []string {
+	matches := find(text, terms)
+
+	var snippets []string
+	for i := 0; i < len(matches) && len(snippets) < limit; {
+		start := snippetStart(text, matches[i][0])
+		end := snippetEnd(text, matches[i][1])
+
+		var b strings.Builder
+		if start > 0 {
+			b.WriteString("…")
+		}
+
+		last := start
+		for ; i < len(matches) && matches[i][1] <= end; i++ {
+			b.WriteString(html.EscapeString(text[last:matches[i][0]]))
+			b.WriteString(markOpen)
+			b.WriteString(html.EscapeString(text[matches[i][0]:matches[i][1]]))
+			b.WriteString(markClose)
+			last = matches[i][1]
+		}
+		b.WriteString(html.EscapeString(text[last:end]))
+
+		if end < len(text) {
+			b.WriteString("…")
+		}
+		snippets = append(snippets, space.ReplaceAllString(b.String(), " "))
+	}
+	return snippets
+}
+
+// find returns the byte ranges of the whole-word occurrences of terms in text, ordered and
+// without overlaps, longer terms winning.
+func find(text string, terms []string) [][2]int {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(terms)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	alternatives := make([]string, len(sorted))
+	for i, term := range sorted {
+		alternatives[i] = strings.ReplaceAll(regexp.QuoteMeta(term), " ", `\s+`)
+	}
+	pattern := regexp.MustCompile(`(?i)` + strings.Join(alternatives, "|"))
+
+	var matches [][2]int
+	for _, m := range pattern.FindAllStringIndex(text, -1) {
+		if isWordBefore(text, m[0]) || isWordAfter(text, m[1]) {
+			continue
+		}
+		matches = append(matches, [2]int{m[0], m[1]})
+	}
+	return matches
+}
+
+func isWordBefore(text string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return i > 0 && isWord(r)
+}
+
+func isWordAfter(text string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return i < len(text) && isWord(r)
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// snippetStart moves back from i by window bytes, to the start of a word.
+func snippetStart(text string, i int) int {
+	if i <= window {
+		return 0
+	}
+	start := i - window
+	if j := strings.IndexFunc(text[start:i], unicode.IsSpace); j >= 0 {
+		_, size := utf8.DecodeRuneInString(text[start+j:])
+		return start + j + size
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	return start
+}
+
+// snippetEnd moves forward from i by window bytes, to the end of a word.
+func snippetEnd(text string, i int) int {
+	if len(text)-i <= window {
+		return len(text)
+	}
+	end := i + window
+	if j := strings.LastIndexFunc(text[i:end], unicode.IsSpace); j >= 0 {
+		return i + j
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	return end
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
+	"github.com/antoniofrisenda/template-service/src/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AliasService manages movable aliases of templates, such as invoice@prod.
+type AliasService interface {
+	PutAlias(ctx context.Context, name string, payload *dto.AliasRequest) (*dto.Alias, error)
+	FindAlias(ctx context.Context, name string) (*dto.Alias, error)
+	DeleteAlias(ctx context.Context, name string) error
+}
+
+type aliasService struct {
+	repo      repository.AliasRepository
+	documents repository.DocumentRepository
+}
+
+func NewAliasService(repo repository.AliasRepository, documents repository.DocumentRepository) AliasService {
+	return &aliasService{repo: repo, documents: documents}
+}
+
+// PutAlias points the alias at the template whose ID or slug is the target, creating the
+// alias when it does not exist.
+func (a *aliasService) PutAlias(ctx context.Context, name string, payload *dto.AliasRequest) (*dto.Alias, error) {
+	ctx = logging.With(ctx, slog.String("alias", name))
+	start := time.Now()
+	logger.InfoContext(ctx, "AliasService.PutAlias", "status", "started", "target", payload.Target)
+
+	if payload.Target == "" || strings.Contains(payload.Target, "@") {
+		err := fmt.Errorf("%w: target must be the ID or slug of a template", ErrAlias)
+		logger.ErrorContext(ctx, "AliasService.PutAlias", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	doc, err := resolve(ctx, a.documents, a.repo, payload.Target)
+	if err != nil {
+		logger.ErrorContext(ctx, "AliasService.PutAlias", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	alias, err := a.repo.Save(ctx, model.NewAlias(name, doc.ID))
+	if err != nil {
+		logger.ErrorContext(ctx, "AliasService.PutAlias", "status", "failure", "step", "saving to DB", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to save alias: %w", err)
+	}
+
+	logger.InfoContext(ctx, "AliasService.PutAlias", "status", "success", "document_id", doc.ID.Hex(), "duration", time.Since(start))
+	return aliasDTO(alias), nil
+}
+
+func (a *aliasService) FindAlias(ctx context.Context, name string) (*dto.Alias, error) {
+	ctx = logging.With(ctx, slog.String("alias", name))
+	start := time.Now()
+	logger.InfoContext(ctx, "AliasService.FindAlias", "status", "started")
+
+	alias, err := a.repo.FindOne(ctx, name)
+	if err != nil {
+		logger.ErrorContext(ctx, "AliasService.FindAlias", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	logger.InfoContext(ctx, "AliasService.FindAlias", "status", "success", "duration", time.Since(start))
+	return aliasDTO(alias), nil
+}
+
+func (a *aliasService) DeleteAlias(ctx context.Context, name string) error {
+	ctx = logging.With(ctx, slog.String("alias", name))
+	start := time.Now()
+	logger.InfoContext(ctx, "AliasService.DeleteAlias", "status", "started")
+
+	if err := a.repo.DeleteOne(ctx, name); err != nil {
+		logger.ErrorContext(ctx, "AliasService.DeleteAlias", "status", "failure", "error", err, "duration", time.Since(start))
+		return err
+	}
+
+	logger.InfoContext(ctx, "AliasService.DeleteAlias", "status", "success", "duration", time.Since(start))
+	return nil
+}
+
+func aliasDTO(alias *model.Alias) *dto.Alias {
+	return &dto.Alias{
+		Name:       alias.Name,
+		DocumentID: alias.DocumentID.Hex(),
+		UpdatedAt:  alias.UpdatedAt,
+	}
+}
+
+// resolve finds a template by reference: an object ID, an alias such as invoice@prod, or a
+// slug such as invoice-standard. Consumers only find published templates.
+func resolve(ctx context.Context, documents repository.DocumentRepository, aliases repository.AliasRepository, ref string) (*model.Document, error) {
+	doc, err := lookup(ctx, documents, aliases, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := visible(ctx, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func lookup(ctx context.Context, documents repository.DocumentRepository, aliases repository.AliasRepository, ref string) (*model.Document, error) {
+	if objID, err := primitive.ObjectIDFromHex(ref); err == nil {
+		return documents.FindOne(ctx, objID)
+	}
+
+	if strings.Contains(ref, "@") {
+		alias, err := aliases.FindOne(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("alias %s: %w", ref, err)
+		}
+		return documents.FindOne(ctx, alias.DocumentID)
+	}
+
+	return documents.FindBySlug(ctx, ref)
+}
+
+// slugConflict reports a write rejected because the slug of the document is already taken.
+func slugConflict(err error, doc *model.Document) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return fmt.Errorf("%w: %s", ErrSlugTaken, doc.Slug)
+	}
+	return err
+}
@@ -7,106 +7,249 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"regexp"
-	"strings"
 	"time"
 
-	"github.com/gofiber/fiber/v3/log"
-
 	"github.com/antoniofrisenda/template-service/src/clients/aws"
+	"github.com/antoniofrisenda/template-service/src/clients/cache"
+	"github.com/antoniofrisenda/template-service/src/clients/ocr"
+	"github.com/antoniofrisenda/template-service/src/clients/scanner"
 	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
 	"github.com/antoniofrisenda/template-service/src/internal/assets/helpers"
 	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
+	"github.com/antoniofrisenda/template-service/src/internal/lint"
+	"github.com/antoniofrisenda/template-service/src/internal/locale"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
+	"github.com/antoniofrisenda/template-service/src/internal/render"
 	"github.com/antoniofrisenda/template-service/src/internal/repository"
-	"github.com/unidoc/unipdf/v3/extractor"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
 	unipdfmodel "github.com/unidoc/unipdf/v3/model"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var regex = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}}`)
+var logger = logging.For("service")
+
+// ParserVersion identifies the current variable extractor. Bump it whenever extraction
+// changes so that ReextractVariables updates the templates stored by older versions.
+// Version 4 stores the text of FILE templates for full-text search.
+const ParserVersion = 4
+
+// reextractBatch is the number of outdated templates ReextractVariables loads at once.
+const reextractBatch = 100
+
+var regex = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*(?:\|[^{}]*)?\}\}`)
 
 type DocumentService interface {
-	ExtractVariables(ctx context.Context, ID string) ([]string, error)
+	ExtractVariables(ctx context.Context, ID string, refresh bool) (*dto.ExtractedVariables, error)
+	LocateVariables(ctx context.Context, ID string) (*dto.ExtractedVariables, error)
+	ReextractVariables(ctx context.Context) (int, error)
 	FindTemplate(ctx context.Context, ID string) (*dto.Document, error)
 
 	FindTemplateWithPresignedURL(ctx context.Context, ID string) (string, error)
+
+	// ListTemplates lists the documents of the tenant matching query, a page at a time.
+	ListTemplates(ctx context.Context, query *dto.DocumentQuery) (*dto.DocumentPage, error)
+
+	// SearchTemplates runs a full-text search over the names, summaries and bodies of the
+	// documents of the tenant matching query.
+	SearchTemplates(ctx context.Context, text string, query *dto.DocumentQuery) (*dto.SearchResults, error)
+
 	InsertTemplate(ctx context.Context, d *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error)
+	UpdateTemplate(ctx context.Context, ID string, d *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error)
+	LintTemplate(ctx context.Context, d *dto.InsertDocument, file *multipart.FileHeader) (*dto.LintReport, error)
+	DeleteTemplate(ctx context.Context, ID string) error
+	RenderTemplate(ctx context.Context, ID string, values map[string]any) (*dto.RenderedDocument, error)
+	RenderBatch(ctx context.Context, ID string, rows []map[string]any) (*dto.BatchResult, io.ReadCloser, error)
+	StoreBatch(ctx context.Context, ID string, rows []map[string]any) (*dto.BatchResult, error)
+	ComposeDocuments(ctx context.Context, payload *dto.ComposeRequest) ([]byte, error)
+	// TransitionTemplate moves a template to another lifecycle state on behalf of the user
+	// of the request.
+	TransitionTemplate(ctx context.Context, ID string, payload *dto.TransitionRequest) (*dto.Document, error)
+
+	PutLocale(ctx context.Context, ID, locale string, payload *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error)
+	DeleteLocale(ctx context.Context, ID, locale string) error
 }
 
 type documentService struct {
-	repo   repository.DocumentRepository
-	mapper helpers.DocumentMapper
-	s3     aws.S3Client
+	repo      repository.DocumentRepository
+	aliases   repository.AliasRepository
+	mapper    helpers.DocumentMapper
+	s3        aws.S3Client
+	buckets   map[string]aws.S3Client
+	scanner   scanner.Scanner
+	ocr       ocr.OCR
+	renderer  render.Renderer
+	sanitizer render.Sanitizer
+	composer  render.Composer
+	linter    lint.Linter
+	cache     cache.Cache
+	cacheTTL  time.Duration
+	limits    ExtractLimits
+
+	// pageText reads the text layer of PDF pages.
+	pageText func(p *unipdfmodel.PdfPage) (string, []int, error)
+
+	// includeDepth bounds how deeply templates may include one another, includeBytes the
+	// size of a template with its includes expanded.
+	includeDepth int
+	includeBytes int
 }
 
-func (d *documentService) ExtractVariables(ctx context.Context, ID string) ([]string, error) {
+// ExtractVariables returns the stored variables of a template. Templates never extracted, or
+// refresh requests, are re-extracted and the stored record is updated when the result differs.
+func (d *documentService) ExtractVariables(ctx context.Context, ID string, refresh bool) (*dto.ExtractedVariables, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
 	start := time.Now()
-	log.WithContext(ctx).Infof("[DocumentService.ExtractVariables] status=started target=%s", ID)
+	logger.InfoContext(ctx, "DocumentService.ExtractVariables", "status", "started", "refresh", refresh)
 
-	objID, err := primitive.ObjectIDFromHex(ID)
+	doc, err := d.find(ctx, ID)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.ExtractVariables] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+		logger.ErrorContext(ctx, "DocumentService.ExtractVariables", "status", "failure", "error", err, "duration", time.Since(start))
 		return nil, err
 	}
 
-	doc, err := d.repo.FindOne(ctx, objID)
-	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.ExtractVariables] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
-		return nil, err
+	localize(ctx, doc)
+
+	// Templates extending a layout or including others are always extracted again, as those
+	// may change.
+	if !refresh && doc.Body != nil && doc.Body.ParserVersion > 0 && !composed(doc) {
+		logger.InfoContext(ctx, "DocumentService.ExtractVariables", "status", "success", "stored", true, "duration", time.Since(start))
+		return &dto.ExtractedVariables{Variables: append([]string{}, doc.Body.Variables...)}, nil
 	}
 
-	extracted, err := d.extractVariables(ctx, doc)
+	extracted, err := d.refreshVariables(ctx, doc)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.ExtractVariables] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+		logger.ErrorContext(ctx, "DocumentService.ExtractVariables", "status", "failure", "error", err, "duration", time.Since(start))
 		return nil, err
 	}
 
-	log.WithContext(ctx).Infof("[DocumentService.ExtractVariables] status=success target=%s duration=%s", ID, time.Since(start))
+	logger.InfoContext(ctx, "DocumentService.ExtractVariables", "status", "success", "duration", time.Since(start))
 	return extracted, nil
 }
 
-func (d *documentService) FindTemplate(ctx context.Context, ID string) (*dto.Document, error) {
+// LocateVariables extracts the variables of a template along with where each occurrence is.
+func (d *documentService) LocateVariables(ctx context.Context, ID string) (*dto.ExtractedVariables, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
 	start := time.Now()
-	log.WithContext(ctx).Infof("[DocumentService.FindTemplate] status=started target=%s", ID)
+	logger.InfoContext(ctx, "DocumentService.LocateVariables", "status", "started")
 
-	objID, err := primitive.ObjectIDFromHex(ID)
+	doc, err := d.find(ctx, ID)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.FindTemplate] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
-		return nil, fmt.Errorf("invalid object id: %w", err)
+		logger.ErrorContext(ctx, "DocumentService.LocateVariables", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
 	}
 
-	doc, err := d.repo.FindOne(ctx, objID)
+	localize(ctx, doc)
+
+	extracted, err := d.analyse(ctx, doc, nil)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.FindTemplate] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+		logger.ErrorContext(ctx, "DocumentService.LocateVariables", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	located := locate(extracted.Pages)
+
+	logger.InfoContext(ctx, "DocumentService.LocateVariables", "status", "success", "duration", time.Since(start))
+	return &dto.ExtractedVariables{
+		Variables:   variableNames(located),
+		Occurrences: located,
+		Diagnostics: extracted.Diagnostics,
+	}, nil
+}
+
+// ReextractVariables re-extracts every template stored with an older ParserVersion, across
+// all tenants, reextractBatch templates at a time. Failures are logged per template and do
+// not stop the run.
+func (d *documentService) ReextractVariables(ctx context.Context) (int, error) {
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.ReextractVariables", "status", "started", "parser_version", ParserVersion)
+
+	var (
+		after    primitive.ObjectID
+		outdated int
+		updated  int
+	)
+
+	for {
+		docs, err := d.repo.FindOutdated(ctx, ParserVersion, after, reextractBatch)
+		if err != nil {
+			logger.ErrorContext(ctx, "DocumentService.ReextractVariables", "status", "failure", "error", err, "updated", updated, "duration", time.Since(start))
+			return updated, err
+		}
+		if len(docs) == 0 {
+			break
+		}
+		outdated += len(docs)
+		after = docs[len(docs)-1].ID
+
+		for i := range docs {
+			if err := ctx.Err(); err != nil {
+				logger.WarnContext(ctx, "DocumentService.ReextractVariables", "status", "cancelled", "updated", updated, "duration", time.Since(start))
+				return updated, err
+			}
+
+			doc := &docs[i]
+			docCtx := logging.With(tenant.WithTenant(ctx, doc.Tenant), slog.String("document_id", doc.ID.Hex()))
+
+			result, err := d.refreshVariables(docCtx, doc)
+			if err != nil {
+				logger.ErrorContext(docCtx, "DocumentService.ReextractVariables", "status", "failure", "error", err)
+				continue
+			}
+			if result.Diagnostics == nil {
+				updated++
+			}
+		}
+
+		if len(docs) < reextractBatch {
+			break
+		}
+	}
+
+	logger.InfoContext(ctx, "DocumentService.ReextractVariables", "status", "success", "outdated", outdated, "updated", updated, "duration", time.Since(start))
+	return updated, nil
+}
+
+func (d *documentService) FindTemplate(ctx context.Context, ID string) (*dto.Document, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
+	ctx = forConsumers(ctx)
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.FindTemplate", "status", "started")
+
+	doc, err := d.find(ctx, ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.FindTemplate", "status", "failure", "error", err, "duration", time.Since(start))
 		return nil, fmt.Errorf("document not found: %w", err)
 	}
 
+	localize(ctx, doc)
+
 	var result *dto.Document
 
 	switch doc.Source {
 	case model.TEXT:
 		result, err = d.mapper.ToDTO(doc)
 		if err != nil {
-			log.WithContext(ctx).Errorf("[DocumentService.FindTemplate] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+			logger.ErrorContext(ctx, "DocumentService.FindTemplate", "status", "failure", "error", err, "duration", time.Since(start))
 			return nil, err
 		}
 
-		log.WithContext(ctx).Infof("[DocumentService.FindTemplate] status=success target=%s duration=%s", ID, time.Since(start))
+		logger.InfoContext(ctx, "DocumentService.FindTemplate", "status", "success", "duration", time.Since(start))
 		return result, nil
 
 	case model.FILE:
-		reader, err := d.s3.Download(ctx, *doc.Body.URL)
-		if err != nil {
-			log.WithContext(ctx).Errorf("[DocumentService.FindTemplate] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
-			return nil, fmt.Errorf("failed to download file: %w", err)
+		if !doc.Scan().IsDownloadable() {
+			logger.ErrorContext(ctx, "DocumentService.FindTemplate", "status", "failure", "error", ErrNotScanned, "duration", time.Since(start))
+			return nil, ErrNotScanned
 		}
-		defer reader.Close()
 
-		content, err := io.ReadAll(reader)
+		content, err := d.download(ctx, *doc.Body.URL, 0)
 		if err != nil {
-			log.WithContext(ctx).Errorf("[DocumentService.FindTemplate] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
-			return nil, fmt.Errorf("failed to read file: %w", err)
+			logger.ErrorContext(ctx, "DocumentService.FindTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+			return nil, fmt.Errorf("failed to download file: %w", err)
 		}
 
 		encoded := base64.StdEncoding.EncodeToString(content)
@@ -115,207 +258,476 @@ func (d *documentService) FindTemplate(ctx context.Context, ID string) (*dto.Doc
 
 		result, err = d.mapper.ToDTO(doc)
 		if err != nil {
-			log.WithContext(ctx).Errorf("[DocumentService.FindTemplate] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+			logger.ErrorContext(ctx, "DocumentService.FindTemplate", "status", "failure", "error", err, "duration", time.Since(start))
 			return nil, err
 		}
 
-		log.WithContext(ctx).Infof("[DocumentService.FindTemplate] status=success target=%s duration=%s", ID, time.Since(start))
+		logger.InfoContext(ctx, "DocumentService.FindTemplate", "status", "success", "duration", time.Since(start))
 		return result, nil
 
 	default:
 		err := fmt.Errorf("unsupported source type: %s", doc.Source)
-		log.WithContext(ctx).Errorf("[DocumentService.FindTemplate] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+		logger.ErrorContext(ctx, "DocumentService.FindTemplate", "status", "failure", "error", err, "duration", time.Since(start))
 		return nil, err
 	}
 }
 
 func (d *documentService) FindTemplateWithPresignedURL(ctx context.Context, ID string) (string, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
+	ctx = forConsumers(ctx)
 	start := time.Now()
-	log.WithContext(ctx).Infof("[DocumentService.FindWithPresignedURL] status=started target=%s", ID)
-
-	objID, err := primitive.ObjectIDFromHex(ID)
-	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.FindWithPresignedURL] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
-		return "", fmt.Errorf("invalid object id: %w", err)
-	}
+	logger.InfoContext(ctx, "DocumentService.FindWithPresignedURL", "status", "started")
 
-	doc, err := d.repo.FindOne(ctx, objID)
+	doc, err := d.find(ctx, ID)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.FindWithPresignedURL] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+		logger.ErrorContext(ctx, "DocumentService.FindWithPresignedURL", "status", "failure", "error", err, "duration", time.Since(start))
 		return "", fmt.Errorf("document not found: %w", err)
 	}
 
+	localize(ctx, doc)
+
 	if doc.Source != model.FILE || doc.Body.URL == nil {
 		err := errors.New("document is not a file")
-		log.WithContext(ctx).Errorf("[DocumentService.FindWithPresignedURL] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+		logger.ErrorContext(ctx, "DocumentService.FindWithPresignedURL", "status", "failure", "error", err, "duration", time.Since(start))
 		return "", err
 	}
 
-	url, err := d.s3.DownloadWithPresignedURL(ctx, *doc.Body.URL, 15*time.Minute)
+	if !doc.Scan().IsDownloadable() {
+		logger.ErrorContext(ctx, "DocumentService.FindWithPresignedURL", "status", "failure", "error", ErrNotScanned, "duration", time.Since(start))
+		return "", ErrNotScanned
+	}
+
+	url, err := d.storage(ctx).DownloadWithPresignedURL(ctx, *doc.Body.URL, 15*time.Minute)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.FindWithPresignedURL] status=failure target=%s error=%v duration=%s", ID, err, time.Since(start))
+		logger.ErrorContext(ctx, "DocumentService.FindWithPresignedURL", "status", "failure", "error", err, "duration", time.Since(start))
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
 
-	log.WithContext(ctx).Infof("[DocumentService.FindWithPresignedURL] status=success target=%s duration=%s", ID, time.Since(start))
+	logger.InfoContext(ctx, "DocumentService.FindWithPresignedURL", "status", "success", "duration", time.Since(start))
 	return url, nil
 }
 
 func (d *documentService) InsertTemplate(ctx context.Context, payload *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error) {
 	start := time.Now()
-	log.WithContext(ctx).Infof("[DocumentService.InsertTemplate] status=started")
+	logger.InfoContext(ctx, "DocumentService.InsertTemplate", "status", "started")
 
 	doc, err := d.mapper.ToModel(payload)
 	if err != nil || doc == nil {
-		log.WithContext(ctx).Errorf("[DocumentService.InsertTemplate] status=failure error=%v duration=%s", err, time.Since(start))
+		logger.ErrorContext(ctx, "DocumentService.InsertTemplate", "status", "failure", "error", err, "duration", time.Since(start))
 		return nil, fmt.Errorf("failed to map payload to model: %w", err)
 	}
 
 	if doc.ID.IsZero() {
 		doc.ID = primitive.NewObjectID()
 	}
+	doc.State = model.DRAFT
 
-	if doc.Type == model.TEMPLATE && doc.Source == model.TEXT && doc.Body.Text != nil {
-		doc.Body.Variables, err = d.extractVariables(ctx, doc)
-		if err != nil {
-			log.WithContext(ctx).Errorf("[DocumentService.InsertTemplate] status=failure extracting variables error=%v duration=%s", err, time.Since(start))
-			return nil, fmt.Errorf("failed to extract variables: %w", err)
-		}
+	ctx = logging.With(ctx, slog.String("document_id", doc.ID.Hex()))
+
+	report, err := d.prepare(ctx, "DocumentService.InsertTemplate", start, doc, file)
+	if err != nil {
+		return nil, err
 	}
 
-	if doc.Source == model.FILE {
-		if file == nil {
-			log.WithContext(ctx).Errorf("[DocumentService.InsertTemplate] status=failure error=file is nil for FILE source")
-			return nil, errors.New("file is required for document of type FILE")
+	inserted, err := d.repo.InsertOne(ctx, doc)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.InsertTemplate", "status", "failure", "step", "inserting to DB", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to insert document: %w", slugConflict(err, doc))
+	}
+
+	result, err := d.mapper.ToDTO(inserted)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.InsertTemplate", "status", "failure", "step", "converting to DTO", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to convert to DTO: %w", err)
+	}
+
+	if report != nil && len(report.Issues) > 0 {
+		result.Lint = report
+	}
+	if report != nil {
+		result.Diagnostics = report.Diagnostics
+	}
+
+	logger.InfoContext(ctx, "DocumentService.InsertTemplate", "status", "success", "duration", time.Since(start))
+	return result, nil
+}
+
+func (d *documentService) RenderTemplate(ctx context.Context, ID string, values map[string]any) (*dto.RenderedDocument, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
+	ctx = forConsumers(ctx)
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.RenderTemplate", "status", "started")
+
+	doc, err := d.find(ctx, ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.RenderTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	localize(ctx, doc)
+
+	if doc.Source != model.TEXT || doc.Body == nil || doc.Body.Text == nil {
+		err := fmt.Errorf("%w: only TEXT documents can be rendered", ErrRender)
+		logger.ErrorContext(ctx, "DocumentService.RenderTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	// The expanded text also keys the cache, so that changes to layouts and included
+	// templates show.
+	text, err := d.expand(ctx, doc)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.RenderTemplate", "status", "failure", "step", "expanding layout and includes", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+	doc.Body.Text = &text
+
+	key, err := renderKey(ctx, doc, values)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.RenderTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("%w: %v", ErrRender, err)
+	}
+
+	if cached, ok := d.cached(ctx, key); ok {
+		logger.InfoContext(ctx, "DocumentService.RenderTemplate", "status", "success", "cache", "hit", "duration", time.Since(start))
+		return &dto.RenderedDocument{
+			ID:          doc.ID.Hex(),
+			ContentType: doc.ContentType,
+			Locale:      doc.Body.Locale,
+			Body:        string(cached),
+		}, nil
+	}
+
+	// Values are formatted for the requested locale, whichever variant was found.
+	rendered, err := d.renderer.Render(doc.ContentType, locale.Preferred(ctx), *doc.Body.Text, values)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.RenderTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("%w: %v", ErrRender, err)
+	}
+
+	d.store(ctx, key, []byte(rendered))
+
+	logger.InfoContext(ctx, "DocumentService.RenderTemplate", "status", "success", "duration", time.Since(start))
+	return &dto.RenderedDocument{
+		ID:          doc.ID.Hex(),
+		ContentType: doc.ContentType,
+		Locale:      doc.Body.Locale,
+		Body:        rendered,
+	}, nil
+}
+
+func (d *documentService) UpdateTemplate(ctx context.Context, ID string, payload *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.UpdateTemplate", "status", "started")
+
+	existing, err := d.find(ctx, ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.UpdateTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	if err := editable(existing); err != nil {
+		logger.ErrorContext(ctx, "DocumentService.UpdateTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	doc, err := d.mapper.ToModel(payload)
+	if err != nil || doc == nil {
+		logger.ErrorContext(ctx, "DocumentService.UpdateTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to map payload to model: %w", err)
+	}
+
+	doc.ID = existing.ID
+	doc.State = existing.State
+	doc.Transitions = existing.Transitions
+
+	// Variants are kept as long as they still match the document, and dropped otherwise.
+	var dropped []string
+	if existing.Type == doc.Type && existing.Source == doc.Source && existing.ContentType == doc.ContentType {
+		doc.Locales = existing.Locales
+	} else {
+		dropped = variantURLs(existing)
+	}
+
+	report, err := d.prepare(ctx, "DocumentService.UpdateTemplate", start, doc, file)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := d.repo.UpdateOne(ctx, doc)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.UpdateTemplate", "status", "failure", "step", "updating DB", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to update document: %w", slugConflict(err, doc))
+	}
+
+	if previous := fileURL(existing); previous != "" && previous != fileURL(updated) {
+		d.invalidate(ctx, fileKey(previous))
+		if err := d.storage(ctx).Delete(ctx, previous); err != nil {
+			logger.WarnContext(ctx, "DocumentService.UpdateTemplate", "status", "orphaned", "step", "deleting previous file", "error", err)
 		}
+	}
+	for _, url := range dropped {
+		d.deleteFile(ctx, "DocumentService.UpdateTemplate", url)
+	}
 
+	result, err := d.mapper.ToDTO(updated)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.UpdateTemplate", "status", "failure", "step", "converting to DTO", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to convert to DTO: %w", err)
+	}
+
+	if report != nil && len(report.Issues) > 0 {
+		result.Lint = report
+	}
+	if report != nil {
+		result.Diagnostics = report.Diagnostics
+	}
+
+	logger.InfoContext(ctx, "DocumentService.UpdateTemplate", "status", "success", "duration", time.Since(start))
+	return result, nil
+}
+
+// LintTemplate reports the lint issues of a template without storing anything.
+func (d *documentService) LintTemplate(ctx context.Context, payload *dto.InsertDocument, file *multipart.FileHeader) (*dto.LintReport, error) {
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.LintTemplate", "status", "started")
+
+	doc, err := d.mapper.ToModel(payload)
+	if err != nil || doc == nil {
+		logger.ErrorContext(ctx, "DocumentService.LintTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to map payload to model: %w", err)
+	}
+
+	if doc.Type != model.TEMPLATE {
+		logger.InfoContext(ctx, "DocumentService.LintTemplate", "status", "success", "duration", time.Since(start))
+		return &dto.LintReport{Issues: []dto.LintIssue{}}, nil
+	}
+
+	var content []byte
+
+	switch {
+	case doc.Source == model.FILE && file != nil:
 		src, err := file.Open()
 		if err != nil {
-			log.WithContext(ctx).Errorf("[DocumentService.InsertTemplate] status=failure error=%v duration=%s", err, time.Since(start))
+			logger.ErrorContext(ctx, "DocumentService.LintTemplate", "status", "failure", "error", err, "duration", time.Since(start))
 			return nil, fmt.Errorf("failed to open file: %w", err)
 		}
 		defer src.Close()
 
-		key := fmt.Sprintf("s3://%s/documents/%s/%s", d.s3.GetBucket(), doc.ID.Hex(), file.Filename)
-
-		if err := d.s3.Upload(ctx, key, src); err != nil {
-			log.WithContext(ctx).Errorf("[DocumentService.InsertTemplate] status=failure uploading to S3 error=%v duration=%s", err, time.Since(start))
-			return nil, fmt.Errorf("failed to upload file to S3: %w", err)
+		content, err = io.ReadAll(src)
+		if err != nil {
+			logger.ErrorContext(ctx, "DocumentService.LintTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+			return nil, fmt.Errorf("failed to read file: %w", err)
 		}
 
-		doc.Body.URL = &key
+		if doc.ContentType == model.HTML {
+			content = []byte(d.sanitizer.Sanitize(string(content)))
+		}
 
-		if doc.Type == model.TEMPLATE {
-			doc.Body.Variables, err = d.extractVariables(ctx, doc)
-			if err != nil {
-				log.WithContext(ctx).Errorf("[DocumentService.InsertTemplate] status=failure extracting variables error=%v duration=%s", err, time.Since(start))
-				return nil, fmt.Errorf("failed to extract variables: %w", err)
-			}
+	case doc.Source == model.TEXT && doc.Body.Text != nil:
+		if doc.ContentType == model.HTML {
+			sanitized := d.sanitizer.Sanitize(*doc.Body.Text)
+			doc.Body.Text = &sanitized
 		}
+
+	default:
+		err := errors.New("template body is required")
+		logger.ErrorContext(ctx, "DocumentService.LintTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
 	}
 
-	inserted, err := d.repo.InsertOne(ctx, doc)
+	extracted, err := d.analyse(ctx, doc, content)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.InsertTemplate] status=failure inserting to DB error=%v duration=%s", err, time.Since(start))
-		return nil, fmt.Errorf("failed to insert document: %w", err)
+		logger.ErrorContext(ctx, "DocumentService.LintTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to extract text: %w", err)
 	}
 
-	result, err := d.mapper.ToDTO(inserted)
+	report := d.linter.Lint(extracted.Pages, declaredNames(doc))
+	report.Diagnostics = extracted.Diagnostics
+
+	logger.InfoContext(ctx, "DocumentService.LintTemplate", "status", "success", "issues", len(report.Issues), "blocking", report.Blocking, "duration", time.Since(start))
+	return report, nil
+}
+
+func (d *documentService) DeleteTemplate(ctx context.Context, ID string) error {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.DeleteTemplate", "status", "started")
+
+	doc, err := d.find(ctx, ID)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[DocumentService.InsertTemplate] status=failure converting to DTO error=%v duration=%s", err, time.Since(start))
-		return nil, fmt.Errorf("failed to convert to DTO: %w", err)
+		logger.ErrorContext(ctx, "DocumentService.DeleteTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return fmt.Errorf("document not found: %w", err)
 	}
 
-	log.WithContext(ctx).Infof("[DocumentService.InsertTemplate] status=success target=%s duration=%s", inserted.ID.Hex(), time.Since(start))
-	return result, nil
+	if err := deletable(auth.FromContext(ctx), doc); err != nil {
+		logger.ErrorContext(ctx, "DocumentService.DeleteTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return err
+	}
+
+	if err := d.repo.DeleteOne(ctx, doc.ID); err != nil {
+		logger.ErrorContext(ctx, "DocumentService.DeleteTemplate", "status", "failure", "step", "deleting from DB", "error", err, "duration", time.Since(start))
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	if url := fileURL(doc); url != "" {
+		d.invalidate(ctx, fileKey(url))
+		if err := d.storage(ctx).Delete(ctx, url); err != nil {
+			logger.WarnContext(ctx, "DocumentService.DeleteTemplate", "status", "orphaned", "step", "deleting file", "error", err)
+		}
+	}
+	for _, url := range variantURLs(doc) {
+		d.deleteFile(ctx, "DocumentService.DeleteTemplate", url)
+	}
+
+	logger.InfoContext(ctx, "DocumentService.DeleteTemplate", "status", "success", "duration", time.Since(start))
+	return nil
 }
 
-func NewDocumentService(repo repository.DocumentRepository, mapper helpers.DocumentMapper, s3 aws.S3Client) DocumentService {
+// NewDocumentService caches file bodies, extracted variables and renders in cache for ttl.
+func NewDocumentService(repo repository.DocumentRepository, aliases repository.AliasRepository, mapper helpers.DocumentMapper, s3 aws.S3Client, buckets map[string]aws.S3Client, scanner scanner.Scanner, ocr ocr.OCR, renderer render.Renderer, sanitizer render.Sanitizer, composer render.Composer, linter lint.Linter, cache cache.Cache, ttl time.Duration, limits ExtractLimits, includeDepth int, includeBytes int) DocumentService {
 	return &documentService{
-		repo:   repo,
-		mapper: mapper,
-		s3:     s3,
+		repo:      repo,
+		aliases:   aliases,
+		mapper:    mapper,
+		s3:        s3,
+		buckets:   buckets,
+		scanner:   scanner,
+		ocr:       ocr,
+		renderer:  renderer,
+		sanitizer: sanitizer,
+		composer:  composer,
+		linter:    linter,
+		cache:     cache,
+		cacheTTL:  ttl,
+		limits:    limits,
+		pageText:  pageText,
+
+		includeDepth: includeDepth,
+		includeBytes: includeBytes,
 	}
 }
 
-func (d *documentService) extractVariables(ctx context.Context, doc *model.Document) ([]string, error) {
-	var content string
+// find loads a template by ID, alias or slug.
+func (d *documentService) find(ctx context.Context, ref string) (*model.Document, error) {
+	return resolve(ctx, d.repo, d.aliases, ref)
+}
 
-	switch doc.Source {
-	case model.TEXT:
-		if doc.Body.Text == nil {
-			return nil, fmt.Errorf("text body is nil")
+func fileURL(doc *model.Document) string {
+	if doc == nil || doc.Source != model.FILE || doc.Body == nil || doc.Body.URL == nil {
+		return ""
+	}
+	return *doc.Body.URL
+}
+
+func (d *documentService) storage(ctx context.Context) aws.S3Client {
+	if client, ok := d.buckets[tenant.Key(ctx)]; ok {
+		return client
+	}
+	return d.s3
+}
+
+// prepare sanitises, scans, lints and uploads the body of doc and extracts its variables,
+// logging failures under operation. Templates whose lint report is blocking are rejected
+// with a *LintError before anything is uploaded.
+func (d *documentService) prepare(ctx context.Context, operation string, start time.Time, doc *model.Document, file *multipart.FileHeader) (*dto.LintReport, error) {
+	if doc.ContentType == model.HTML && doc.Source == model.TEXT && doc.Body.Text != nil {
+		sanitized := d.sanitizer.Sanitize(*doc.Body.Text)
+		doc.Body.Text = &sanitized
+	}
+
+	var content []byte
+
+	if doc.Source == model.FILE {
+		if file == nil {
+			logger.ErrorContext(ctx, operation, "status", "failure", "error", "file is nil for FILE source")
+			return nil, errors.New("file is required for document of type FILE")
 		}
-		content = *doc.Body.Text
 
-	case model.FILE:
-		if doc.Body.URL == nil {
-			return nil, fmt.Errorf("file URL is nil")
+		src, err := file.Open()
+		if err != nil {
+			logger.ErrorContext(ctx, operation, "status", "failure", "error", err, "duration", time.Since(start))
+			return nil, fmt.Errorf("failed to open file: %w", err)
 		}
-		reader, err := d.s3.Download(ctx, *doc.Body.URL)
+		defer src.Close()
+
+		scan, err := d.scanner.Scan(ctx, src)
 		if err != nil {
-			return nil, fmt.Errorf("failed to download file: %w", err)
+			logger.ErrorContext(ctx, operation, "status", "failure", "step", "scanning file", "error", err, "duration", time.Since(start))
+			return nil, fmt.Errorf("%w: %v", ErrScanFailure, err)
 		}
-		defer reader.Close()
 
-		Bytes, err := io.ReadAll(reader)
+		if scan.Infected {
+			logger.WarnContext(ctx, operation, "status", "rejected", "signature", scan.Signature, "duration", time.Since(start))
+			return nil, fmt.Errorf("%w: %s", ErrInfected, scan.Signature)
+		}
+
+		doc.ScanStatus = model.CLEAN
+
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			logger.ErrorContext(ctx, operation, "status", "failure", "error", err, "duration", time.Since(start))
+			return nil, fmt.Errorf("failed to rewind file: %w", err)
+		}
+
+		content, err = io.ReadAll(src)
 		if err != nil {
+			logger.ErrorContext(ctx, operation, "status", "failure", "error", err, "duration", time.Since(start))
 			return nil, fmt.Errorf("failed to read file: %w", err)
 		}
 
-		if doc.ContentType == model.PDF {
-			pdf, err := unipdfmodel.NewPdfReader(bytes.NewReader(Bytes))
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse pdf: %w", err)
-			}
+		if doc.ContentType == model.HTML {
+			content = []byte(d.sanitizer.Sanitize(string(content)))
+		}
+	}
 
-			pages, err := pdf.GetNumPages()
-			if err != nil {
-				return nil, fmt.Errorf("failed to read pdf page count: %w", err)
-			}
+	var report *dto.LintReport
 
-			var textBuilder strings.Builder
-			for i := 1; i <= pages; i++ {
-				page, err := pdf.GetPage(i)
-				if err != nil {
-					return nil, fmt.Errorf("failed to get pdf page %d: %w", i, err)
-				}
-
-				Exctractor, err := extractor.New(page)
-				if err != nil {
-					return nil, fmt.Errorf("failed to init extractor for pdf page %d: %w", i, err)
-				}
-
-				Text, err := Exctractor.ExtractText()
-				if err != nil {
-					return nil, fmt.Errorf("failed to extract text from pdf page %d: %w", i, err)
-				}
-
-				_, err = textBuilder.WriteString(Text)
-				if err != nil {
-					return nil, err
-				}
-			}
+	if doc.Type == model.TEMPLATE && (doc.Source == model.FILE || doc.Body.Text != nil) {
+		extracted, err := d.analyse(ctx, doc, content)
+		if err != nil {
+			logger.ErrorContext(ctx, operation, "status", "failure", "step", "extracting variables", "error", err, "duration", time.Since(start))
+			return nil, fmt.Errorf("failed to extract variables: %w", err)
+		}
 
-			content = textBuilder.String()
-		} else {
-			content = string(Bytes)
+		report = d.linter.Lint(extracted.Pages, declaredNames(doc))
+		report.Diagnostics = extracted.Diagnostics
+		if report.Blocking {
+			logger.WarnContext(ctx, operation, "status", "rejected", "step", "linting", "issues", len(report.Issues), "duration", time.Since(start))
+			return report, &LintError{Report: report}
 		}
-	default:
-		return nil, fmt.Errorf("unsupported source type: %s", doc.Source)
-	}
 
-	matched := make(map[string]struct{})
-	for _, m := range regex.FindAllStringSubmatch(content, -1) {
-		if len(m) > 1 {
-			matched[m[1]] = struct{}{}
+		// Partial extractions keep a zero ParserVersion, so that they are retried later.
+		doc.Body.Variables = variableNames(locate(extracted.Pages))
+		doc.Body.Content = searchContent(doc, extracted.Pages)
+		if extracted.Diagnostics == nil {
+			doc.Body.ParserVersion = ParserVersion
 		}
 	}
 
-	variables := make([]string, 0, len(matched))
-	for k := range matched {
-		variables = append(variables, k)
+	if doc.Source == model.FILE {
+		tenantID, err := tenant.FromContext(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, operation, "status", "failure", "error", err, "duration", time.Since(start))
+			return nil, err
+		}
+
+		// Variants are stored under their locale, so they never overwrite the default file.
+		name := file.Filename
+		if doc.Body.Locale != "" {
+			name = doc.Body.Locale + "/" + name
+		}
+
+		storage := d.storage(ctx)
+		key := fmt.Sprintf("s3://%s/tenants/%s/documents/%s/%s", storage.GetBucket(), tenantID, doc.ID.Hex(), name)
+
+		if err := storage.Upload(ctx, key, bytes.NewReader(content)); err != nil {
+			logger.ErrorContext(ctx, operation, "status", "failure", "step", "uploading to S3", "error", err, "duration", time.Since(start))
+			return nil, fmt.Errorf("failed to upload file to S3: %w", err)
+		}
+
+		d.invalidate(ctx, fileKey(key))
+		doc.Body.URL = &key
 	}
 
-	return variables, nil
+	return report, nil
 }
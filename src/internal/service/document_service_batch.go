@@ -0,0 +1,179 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/locale"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// batchErrorsFile lists the rows that failed, inside the archive, when any did.
+const batchErrorsFile = "errors.json"
+
+// RenderBatch renders template ID once per row into a ZIP archive, with one entry per rendered
+// row and the failed rows listed in errors.json. The archive is spooled to a temporary file,
+// removed when the returned reader is closed.
+func (d *documentService) RenderBatch(ctx context.Context, ID string, rows []map[string]any) (*dto.BatchResult, io.ReadCloser, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
+	ctx = forConsumers(ctx)
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.RenderBatch", "status", "started", "rows", len(rows))
+
+	result, archive, err := d.renderBatch(ctx, ID, rows)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.RenderBatch", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, nil, err
+	}
+
+	logger.InfoContext(ctx, "DocumentService.RenderBatch", "status", "success", "rendered", result.Rendered, "failed", result.Failed, "duration", time.Since(start))
+	return result, archive, nil
+}
+
+// StoreBatch renders a batch like RenderBatch and uploads the archive to S3, returning a
+// presigned URL to it.
+func (d *documentService) StoreBatch(ctx context.Context, ID string, rows []map[string]any) (*dto.BatchResult, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID))
+	ctx = forConsumers(ctx)
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.StoreBatch", "status", "started", "rows", len(rows))
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.StoreBatch", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	result, archive, err := d.renderBatch(ctx, ID, rows)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.StoreBatch", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+	defer archive.Close()
+
+	storage := d.storage(ctx)
+	key := fmt.Sprintf("s3://%s/tenants/%s/batches/%s/%s.zip", storage.GetBucket(), tenantID, ID, primitive.NewObjectID().Hex())
+
+	if err := storage.Upload(ctx, key, archive); err != nil {
+		logger.ErrorContext(ctx, "DocumentService.StoreBatch", "status", "failure", "step", "uploading to S3", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to upload batch to S3: %w", err)
+	}
+
+	url, err := storage.DownloadWithPresignedURL(ctx, key, 15*time.Minute)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.StoreBatch", "status", "failure", "step", "presigning", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+	result.URL = url
+
+	logger.InfoContext(ctx, "DocumentService.StoreBatch", "status", "success", "rendered", result.Rendered, "failed", result.Failed, "duration", time.Since(start))
+	return result, nil
+}
+
+// renderBatch checks that the template can be rendered before writing the archive, so that
+// such errors fail the whole batch while render errors of single rows are only collected.
+func (d *documentService) renderBatch(ctx context.Context, ID string, rows []map[string]any) (*dto.BatchResult, *spooledFile, error) {
+	doc, err := d.find(ctx, ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	localize(ctx, doc)
+
+	if doc.Source != model.TEXT || doc.Body == nil || doc.Body.Text == nil {
+		return nil, nil, fmt.Errorf("%w: only TEXT documents can be rendered", ErrRender)
+	}
+
+	text, err := d.expand(ctx, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc.Body.Text = &text
+
+	file, err := os.CreateTemp("", "batch-*.zip")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create batch archive: %w", err)
+	}
+	archive := &spooledFile{File: file}
+
+	result, err := d.writeBatch(ctx, doc, rows, archive)
+	if err == nil {
+		_, err = archive.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		archive.Close()
+		return nil, nil, fmt.Errorf("failed to write batch archive: %w", err)
+	}
+
+	return result, archive, nil
+}
+
+func (d *documentService) writeBatch(ctx context.Context, doc *model.Document, rows []map[string]any, w io.Writer) (*dto.BatchResult, error) {
+	result := &dto.BatchResult{Rows: len(rows), Errors: []dto.RowError{}}
+	archive := zip.NewWriter(w)
+	extension := batchExtension(doc.ContentType)
+
+	for i, values := range rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		rendered, err := d.renderer.Render(doc.ContentType, locale.Preferred(ctx), *doc.Body.Text, values)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, dto.RowError{Row: i + 1, Error: err.Error()})
+			continue
+		}
+
+		entry, err := archive.Create(fmt.Sprintf("%06d%s", i+1, extension))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, rendered); err != nil {
+			return nil, err
+		}
+		result.Rendered++
+	}
+
+	if result.Failed > 0 {
+		entry, err := archive.Create(batchErrorsFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.NewEncoder(entry).Encode(result.Errors); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, archive.Close()
+}
+
+func batchExtension(contentType model.ContentType) string {
+	if contentType == model.HTML {
+		return ".html"
+	}
+	return ".txt"
+}
+
+// spooledFile is a temporary file deleted on Close.
+type spooledFile struct {
+	*os.File
+}
+
+func (f *spooledFile) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
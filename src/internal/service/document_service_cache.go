@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/locale"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+)
+
+// fileKey is derived from the S3 URL, which already embeds the bucket, tenant and document.
+func fileKey(url string) string {
+	return "file:" + url
+}
+
+// renderKey hashes everything the output depends on, so edits to the body produce a new key
+// and stale renders simply expire.
+func renderKey(ctx context.Context, doc *model.Document, values map[string]any) (string, error) {
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{tenant.Key(ctx), locale.Preferred(ctx), string(doc.ContentType), *doc.Body.Text, string(encoded)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	return "render:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// cached reads key from the cache, treating cache failures as misses.
+func (d *documentService) cached(ctx context.Context, key string) ([]byte, bool) {
+	value, ok, err := d.cache.Get(ctx, key)
+	if err != nil {
+		logger.WarnContext(ctx, "DocumentService.cache", "status", "failure", "key", key, "error", err)
+		return nil, false
+	}
+	return value, ok
+}
+
+func (d *documentService) store(ctx context.Context, key string, value []byte) {
+	if err := d.cache.Set(ctx, key, value, d.cacheTTL); err != nil {
+		logger.WarnContext(ctx, "DocumentService.cache", "status", "failure", "key", key, "error", err)
+	}
+}
+
+func (d *documentService) invalidate(ctx context.Context, keys ...string) {
+	if err := d.cache.Delete(ctx, keys...); err != nil {
+		logger.WarnContext(ctx, "DocumentService.cache", "status", "failure", "keys", keys, "error", err)
+	}
+}
+
+// download returns the content of the S3 object at url, serving it from cache when possible.
+// Objects larger than a positive limit fail with errFileTooLarge without being read fully.
+func (d *documentService) download(ctx context.Context, url string, limit int64) ([]byte, error) {
+	key := fileKey(url)
+	if content, ok := d.cached(ctx, key); ok {
+		if limit > 0 && int64(len(content)) > limit {
+			return nil, errFileTooLarge
+		}
+		return content, nil
+	}
+
+	reader, err := d.storage(ctx).Download(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var body io.Reader = reader
+	if limit > 0 {
+		body = io.LimitReader(reader, limit+1)
+	}
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && int64(len(content)) > limit {
+		return nil, errFileTooLarge
+	}
+
+	d.store(ctx, key, content)
+	return content, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/locale"
+	"github.com/antoniofrisenda/template-service/src/internal/render"
+)
+
+// ComposeDocuments merges the listed documents into a single PDF, in order. TEMPLATE documents
+// are rendered with the variables of their part first; PDF and IMAGE files are included as
+// they are.
+func (d *documentService) ComposeDocuments(ctx context.Context, payload *dto.ComposeRequest) ([]byte, error) {
+	ctx = forConsumers(ctx)
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.ComposeDocuments", "status", "started", "documents", len(payload.Documents))
+
+	sections := make([]render.Section, len(payload.Documents))
+	for i, part := range payload.Documents {
+		section, err := d.section(ctx, part)
+		if err != nil {
+			logger.ErrorContext(ctx, "DocumentService.ComposeDocuments", "status", "failure", "document_id", part.ID, "error", err, "duration", time.Since(start))
+			return nil, err
+		}
+		sections[i] = *section
+	}
+
+	var buf bytes.Buffer
+	options := render.ComposeOptions{
+		Bookmarks:       payload.Bookmarks,
+		PageNumbers:     payload.PageNumbers,
+		TableOfContents: payload.TableOfContents,
+	}
+
+	if err := d.composer.Compose(sections, options, &buf); err != nil {
+		logger.ErrorContext(ctx, "DocumentService.ComposeDocuments", "status", "failure", "step", "composing", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("%w: %v", ErrRender, err)
+	}
+
+	logger.InfoContext(ctx, "DocumentService.ComposeDocuments", "status", "success", "bytes", buf.Len(), "duration", time.Since(start))
+	return buf.Bytes(), nil
+}
+
+// section loads the content of a composed document, rendering it when it is a template.
+func (d *documentService) section(ctx context.Context, part dto.ComposePart) (*render.Section, error) {
+	doc, err := d.find(ctx, part.ID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	localize(ctx, doc)
+
+	section := &render.Section{Title: part.Title, ContentType: doc.ContentType}
+	if section.Title == "" {
+		section.Title = doc.Name
+	}
+
+	switch {
+	case doc.Body == nil:
+		return nil, fmt.Errorf("%w: document %s has no body", ErrRender, part.ID)
+
+	case doc.Source == model.TEXT && doc.Body.Text != nil:
+		text := *doc.Body.Text
+		if doc.Type == model.TEMPLATE {
+			if text, err = d.expand(ctx, doc); err != nil {
+				return nil, err
+			}
+			if text, err = d.renderer.Render(doc.ContentType, locale.Preferred(ctx), text, part.Variables); err != nil {
+				return nil, fmt.Errorf("%w: document %s: %v", ErrRender, part.ID, err)
+			}
+		}
+		section.Content = []byte(text)
+
+	case doc.Source == model.FILE && doc.Type == model.STATIC && doc.Body.URL != nil:
+		if !doc.Scan().IsDownloadable() {
+			return nil, ErrNotScanned
+		}
+
+		if section.Content, err = d.download(ctx, *doc.Body.URL, 0); err != nil {
+			return nil, fmt.Errorf("failed to download file: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: document %s: only TEXT templates can be rendered", ErrRender, part.ID)
+	}
+
+	return section, nil
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/lint"
+	"github.com/antoniofrisenda/template-service/src/internal/metrics"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/extractor"
+	unipdfmodel "github.com/unidoc/unipdf/v3/model"
+	pdfrender "github.com/unidoc/unipdf/v3/render"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ExtractLimits bounds the work spent extracting the text of a single document. Zero values
+// disable the corresponding limit.
+type ExtractLimits struct {
+	Workers  int
+	MaxPages int
+	MaxBytes int64
+	Timeout  time.Duration
+}
+
+var errFileTooLarge = errors.New("file exceeds the extraction size limit")
+
+// maxSearchContent bounds the text of a file kept for full-text search, well below the size
+// limit of a Mongo document.
+const maxSearchContent = 1 << 20
+
+// extraction is the text extracted from a document. Diagnostics is set when limits or page
+// failures left it incomplete.
+type extraction struct {
+	Pages       []lint.Page
+	Diagnostics *dto.Diagnostics
+}
+
+// refreshVariables extracts the variables of doc and stores them, along with the current
+// ParserVersion, when they differ from the stored ones. Partial results are never stored.
+func (d *documentService) refreshVariables(ctx context.Context, doc *model.Document) (*dto.ExtractedVariables, error) {
+	extracted, err := d.analyse(ctx, doc, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	located := locate(extracted.Pages)
+	content := searchContent(doc, extracted.Pages)
+	result := &dto.ExtractedVariables{
+		Variables:   variableNames(located),
+		Diagnostics: extracted.Diagnostics,
+	}
+
+	if extracted.Diagnostics != nil {
+		logger.WarnContext(ctx, "DocumentService.refreshVariables", "status", "partial", "reasons", extracted.Diagnostics.Reasons, "errors", len(extracted.Diagnostics.Errors))
+		return result, nil
+	}
+
+	// Variants keep the variables extracted when they were stored; only the default body is
+	// updated here.
+	if doc.Body.Locale != "" || (doc.Body.ParserVersion == ParserVersion && slices.Equal(doc.Body.Variables, result.Variables) && doc.Body.Content == content) {
+		return result, nil
+	}
+
+	if err := d.repo.UpdateVariables(ctx, doc.ID, result.Variables, content, ParserVersion); err != nil {
+		return nil, fmt.Errorf("failed to store variables: %w", err)
+	}
+
+	logger.InfoContext(ctx, "DocumentService.refreshVariables", "status", "updated", "previous", doc.Body.Variables, "variables", result.Variables)
+	return result, nil
+}
+
+// searchContent returns the text of the pages of a FILE template, truncated to
+// maxSearchContent, for full-text search. TEXT bodies are searched as they are.
+func searchContent(doc *model.Document, pages []lint.Page) string {
+	if doc.Source != model.FILE {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, page := range pages {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(page.Text)
+		if b.Len() >= maxSearchContent {
+			break
+		}
+	}
+
+	content := b.String()
+	if len(content) > maxSearchContent {
+		content = strings.ToValidUTF8(content[:maxSearchContent], "")
+	}
+	return strings.TrimSpace(content)
+}
+
+func declaredNames(doc *model.Document) []string {
+	names := make([]string, len(doc.Body.Declared))
+	for i, v := range doc.Body.Declared {
+		names[i] = v.Name
+	}
+	return names
+}
+
+func variableNames(located []dto.Variable) []string {
+	variables := make([]string, len(located))
+	for i, v := range located {
+		variables[i] = v.Name
+	}
+	return variables
+}
+
+// analyse extracts the text of doc within the configured timeout, recording the extraction
+// metrics and span. For FILE documents content is used when given, otherwise the file is
+// downloaded.
+func (d *documentService) analyse(ctx context.Context, doc *model.Document, content []byte) (*extraction, error) {
+	start := time.Now()
+	defer func() {
+		metrics.ExtractionDuration.WithLabelValues(string(doc.ContentType)).Observe(metrics.Since(start))
+	}()
+
+	ctx, span := startSpan(ctx, "DocumentService.extractVariables",
+		attribute.String("document.content_type", string(doc.ContentType)),
+		attribute.String("document.source", string(doc.Source)),
+	)
+	defer span.End()
+
+	if d.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.limits.Timeout)
+		defer cancel()
+	}
+
+	extracted, err := d.extract(ctx, doc, content)
+	if err == nil && extracted.Diagnostics != nil {
+		span.SetAttributes(attribute.Bool("extraction.partial", true))
+	}
+	return extracted, endSpan(span, err)
+}
+
+func (d *documentService) extract(ctx context.Context, doc *model.Document, content []byte) (*extraction, error) {
+	switch doc.Source {
+	case model.TEXT:
+		if doc.Body.Text == nil {
+			return nil, fmt.Errorf("text body is nil")
+		}
+		text, err := d.expand(ctx, doc)
+		if err != nil {
+			return nil, err
+		}
+		return &extraction{Pages: []lint.Page{{Text: text}}}, nil
+
+	case model.FILE:
+		if content == nil {
+			if doc.Body.URL == nil {
+				return nil, fmt.Errorf("file URL is nil")
+			}
+			if !doc.Scan().IsDownloadable() {
+				return nil, ErrNotScanned
+			}
+
+			var err error
+			content, err = d.download(ctx, *doc.Body.URL, d.limits.MaxBytes)
+			if errors.Is(err, errFileTooLarge) {
+				return d.tooLarge(), nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to download file: %w", err)
+			}
+		}
+
+		if d.limits.MaxBytes > 0 && int64(len(content)) > d.limits.MaxBytes {
+			return d.tooLarge(), nil
+		}
+
+		return d.extractFile(ctx, doc.ContentType, content)
+
+	default:
+		return nil, fmt.Errorf("unsupported source type: %s", doc.Source)
+	}
+}
+
+func (d *documentService) tooLarge() *extraction {
+	return &extraction{Diagnostics: &dto.Diagnostics{
+		Partial: true,
+		Reasons: []string{fmt.Sprintf("file exceeds the %d bytes extraction limit", d.limits.MaxBytes)},
+	}}
+}
+
+// extractFile returns the text of a file. Images go through OCR.
+func (d *documentService) extractFile(ctx context.Context, contentType model.ContentType, Bytes []byte) (*extraction, error) {
+	switch contentType {
+	case model.PDF:
+		return d.extractPDF(ctx, Bytes)
+
+	case model.IMAGE:
+		text, err := d.recognize(ctx, bytes.NewReader(Bytes), 0)
+		if err != nil && ctx.Err() != nil {
+			return &extraction{Diagnostics: &dto.Diagnostics{Partial: true, Reasons: []string{"extraction timed out"}}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &extraction{Pages: []lint.Page{{Text: text}}}, nil
+
+	default:
+		return &extraction{Pages: []lint.Page{{Text: string(Bytes)}}}, nil
+	}
+}
+
+// extractPDF extracts pages concurrently with a bounded pool of workers, each with its own
+// reader since unipdf readers are not safe for concurrent use: the first worker reuses the
+// reader that counted the pages, the others parse theirs once they are handed a page. When
+// ctx expires, workers stop taking pages and the pages extracted so far are returned once
+// the pages in progress finish, as unipdf cannot be interrupted; at most one page per worker
+// is in flight.
+func (d *documentService) extractPDF(ctx context.Context, Bytes []byte) (*extraction, error) {
+	ctx, span := startSpan(ctx, "pdf.ExtractText", attribute.Int("pdf.bytes", len(Bytes)))
+	defer span.End()
+
+	pdf, err := unipdfmodel.NewPdfReader(bytes.NewReader(Bytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pdf: %w", err)
+	}
+
+	count, err := pdf.GetNumPages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pdf page count: %w", err)
+	}
+	metrics.PDFPages.Observe(float64(count))
+	span.SetAttributes(attribute.Int("pdf.pages", count))
+
+	diagnostics := &dto.Diagnostics{Pages: count}
+
+	limit := count
+	if d.limits.MaxPages > 0 && count > d.limits.MaxPages {
+		limit = d.limits.MaxPages
+		diagnostics.Reasons = append(diagnostics.Reasons, fmt.Sprintf("only the first %d of %d pages were extracted", limit, count))
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		pages    = make([]*lint.Page, limit)
+		failures []dto.PageError
+	)
+
+	numbers := make(chan int)
+	go func() {
+		defer close(numbers)
+		for n := 1; n <= limit; n++ {
+			select {
+			case numbers <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for worker := range max(1, min(d.limits.Workers, limit)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var (
+				reader    *unipdfmodel.PdfReader
+				readerErr error
+			)
+			if worker == 0 {
+				reader = pdf
+			}
+
+			for n := range numbers {
+				if ctx.Err() != nil {
+					return
+				}
+
+				if reader == nil && readerErr == nil {
+					reader, readerErr = unipdfmodel.NewPdfReader(bytes.NewReader(Bytes))
+				}
+
+				page, err := (*lint.Page)(nil), readerErr
+				if err == nil {
+					page, err = d.extractPage(ctx, reader, n)
+				}
+
+				// Pages cut short by the timeout are reported as such, not as failures.
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+
+				mu.Lock()
+				if err != nil {
+					failures = append(failures, dto.PageError{Page: n, Error: err.Error()})
+				} else {
+					pages[n-1] = page
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	result := make([]lint.Page, 0, limit)
+	for _, p := range pages {
+		if p != nil {
+			result = append(result, *p)
+		}
+	}
+
+	diagnostics.ExtractedPages = len(result)
+	diagnostics.Errors = slices.Clone(failures)
+	if len(result)+len(failures) < limit {
+		diagnostics.Reasons = append(diagnostics.Reasons, "extraction timed out")
+	}
+
+	if len(diagnostics.Reasons) == 0 && len(diagnostics.Errors) == 0 {
+		return &extraction{Pages: result}, nil
+	}
+
+	diagnostics.Partial = true
+	slices.SortFunc(diagnostics.Errors, func(a, b dto.PageError) int { return a.Page - b.Page })
+	span.SetAttributes(attribute.Int("pdf.extracted_pages", len(result)))
+
+	return &extraction{Pages: result, Diagnostics: diagnostics}, nil
+}
+
+// extractPage returns the text of a PDF page, recording where each text object starts so
+// that placeholders split across objects can be reported. Pages without a text layer go
+// through OCR.
+func (d *documentService) extractPage(ctx context.Context, reader *unipdfmodel.PdfReader, number int) (*lint.Page, error) {
+	p, err := reader.GetPage(number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pdf page %d: %w", number, err)
+	}
+
+	text, runs, err := d.pageText(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text from pdf page %d: %w", number, err)
+	}
+
+	if strings.TrimSpace(text) == "" {
+		text, err = d.recognizePage(ctx, p, number)
+		if err != nil {
+			return nil, err
+		}
+		return &lint.Page{Number: number, Text: text}, nil
+	}
+
+	return &lint.Page{Number: number, Text: text, Runs: runs}, nil
+}
+
+// pageText returns the text layer of a PDF page and the offsets at which its text objects,
+// after the first, start.
+func pageText(p *unipdfmodel.PdfPage) (string, []int, error) {
+	Exctractor, err := extractor.New(p)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to init extractor: %w", err)
+	}
+
+	pageText, _, _, err := Exctractor.ExtractPageText()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		runs     []int
+		previous core.PdfObject
+	)
+	for _, mark := range pageText.Marks().Elements() {
+		if mark.Meta || mark.DirectObject == nil {
+			continue
+		}
+		if previous != nil && mark.DirectObject != previous {
+			runs = append(runs, mark.Offset)
+		}
+		previous = mark.DirectObject
+	}
+
+	return pageText.Text(), runs, nil
+}
+
+// recognizePage renders a PDF page without a text layer and runs it through OCR.
+func (d *documentService) recognizePage(ctx context.Context, p *unipdfmodel.PdfPage, number int) (string, error) {
+	img, err := pdfrender.NewImageDevice().Render(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to render pdf page %d: %w", number, err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("failed to encode pdf page %d: %w", number, err)
+	}
+
+	return d.recognize(ctx, &buf, number)
+}
+
+func (d *documentService) recognize(ctx context.Context, image io.Reader, page int) (string, error) {
+	ctx, span := startSpan(ctx, "ocr.Recognize", attribute.Int("pdf.page", page))
+	defer span.End()
+
+	text, err := d.ocr.Recognize(ctx, image)
+	if err != nil {
+		return "", endSpan(span, fmt.Errorf("failed to recognize text: %w", err))
+	}
+
+	span.SetAttributes(attribute.Int("ocr.characters", len(text)))
+	return text, nil
+}
+
+// locate lists the placeholders found in pages in order of first appearance, with the
+// position of every occurrence.
+func locate(pages []lint.Page) []dto.Variable {
+	variables := []dto.Variable{}
+	index := make(map[string]int)
+
+	for _, p := range pages {
+		for _, m := range regex.FindAllStringSubmatchIndex(p.Text, -1) {
+			name := p.Text[m[2]:m[3]]
+
+			i, ok := index[name]
+			if !ok {
+				i = len(variables)
+				index[name] = i
+				variables = append(variables, dto.Variable{Name: name})
+			}
+
+			variables[i].Count++
+			variables[i].Positions = append(variables[i].Positions, lint.Position(p, m[0]))
+		}
+	}
+
+	return variables
+}
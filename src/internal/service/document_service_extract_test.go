@@ -0,0 +1,234 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/clients/ocr"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/lint"
+	unipdfmodel "github.com/unidoc/unipdf/v3/model"
+)
+
+// testPDF builds a PDF with one page per text, drawn as a single text object. Empty texts
+// give pages without a text layer.
+func testPDF(texts ...string) []byte {
+	kids := make([]string, len(texts))
+	for i := range texts {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+2*i)
+	}
+	font := 3 + 2*len(texts)
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(texts)),
+	}
+	for i, text := range texts {
+		var stream string
+		if text != "" {
+			stream = "BT /F1 12 Tf 72 720 Td (" + text + ") Tj ET"
+		}
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>", font, 4+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+var showText = regexp.MustCompile(`\((.*)\) Tj`)
+
+// streamText stands in for the unipdf extractor, which requires a license, by reading the
+// text shown by the content streams of testPDF.
+func streamText(p *unipdfmodel.PdfPage) (string, []int, error) {
+	content, err := p.GetAllContentStreams()
+	if err != nil {
+		return "", nil, err
+	}
+	var text []string
+	for _, m := range showText.FindAllStringSubmatch(content, -1) {
+		text = append(text, m[1])
+	}
+	return strings.Join(text, "\n"), nil, nil
+}
+
+// blockingOCR recognises nothing until ctx is done, tracking the calls in flight.
+type blockingOCR struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	calls    int
+}
+
+func (b *blockingOCR) Recognize(ctx context.Context, image io.Reader) (string, error) {
+	b.mu.Lock()
+	b.inFlight++
+	b.calls++
+	b.peak = max(b.peak, b.inFlight)
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.inFlight--
+		b.mu.Unlock()
+	}()
+
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestExtractPDFTimeoutReleasesWorkers(t *testing.T) {
+	recognizer := &blockingOCR{}
+	d := &documentService{
+		ocr:      recognizer,
+		pageText: streamText,
+		limits:   ExtractLimits{Workers: 3},
+	}
+
+	texts := make([]string, 12)
+	texts[0] = "Dear {{ name }}"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	extracted, err := d.extractPDF(ctx, testPDF(texts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recognizer.mu.Lock()
+	inFlight, peak, calls := recognizer.inFlight, recognizer.peak, recognizer.calls
+	recognizer.mu.Unlock()
+
+	if inFlight != 0 {
+		t.Fatalf("%d pages still being recognised after extractPDF returned", inFlight)
+	}
+	if peak > 3 {
+		t.Fatalf("%d pages recognised at once with 3 workers", peak)
+	}
+	if calls > 3 {
+		t.Fatalf("%d pages handed to OCR after the timeout", calls)
+	}
+
+	diagnostics := extracted.Diagnostics
+	if diagnostics == nil || !diagnostics.Partial || len(diagnostics.Errors) != 0 {
+		t.Fatalf("diagnostics = %+v, want a partial result without page errors", diagnostics)
+	}
+	if !strings.Contains(strings.Join(diagnostics.Reasons, ";"), "timed out") {
+		t.Fatalf("reasons = %v, want a timeout", diagnostics.Reasons)
+	}
+	if len(extracted.Pages) != 1 || extracted.Pages[0].Text != "Dear {{ name }}" {
+		t.Fatalf("pages = %+v, want the text page", extracted.Pages)
+	}
+}
+
+func TestExtractFileOCR(t *testing.T) {
+	recognizer := ocr.NewFakeOCR("Signed {{ signature }}")
+	d := &documentService{
+		ocr:      recognizer,
+		pageText: streamText,
+		limits:   ExtractLimits{Workers: 2},
+	}
+	ctx := context.Background()
+
+	extracted, err := d.extractFile(ctx, model.PDF, testPDF("Dear {{ name }}", "", "Total {{ total }}", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extracted.Diagnostics != nil {
+		t.Fatalf("diagnostics = %+v", extracted.Diagnostics)
+	}
+
+	// Only the two pages without a text layer are rendered and recognised.
+	if images := recognizer.Images(); !slices.Equal(images, []string{"png", "png"}) {
+		t.Fatalf("OCR got %v, want the two image-only pages as png", images)
+	}
+	want := []string{"Dear {{ name }}", "Signed {{ signature }}", "Total {{ total }}", "Signed {{ signature }}"}
+	for i, page := range extracted.Pages {
+		if page.Number != i+1 || page.Text != want[i] {
+			t.Errorf("page %d = %d %q, want %q", i+1, page.Number, page.Text, want[i])
+		}
+	}
+	if names := variableNames(locate(extracted.Pages)); !slices.Equal(names, []string{"name", "signature", "total"}) {
+		t.Errorf("variables = %v", names)
+	}
+
+	var scan bytes.Buffer
+	if err := png.Encode(&scan, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	extracted, err = d.extractFile(ctx, model.IMAGE, scan.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extracted.Pages) != 1 || extracted.Pages[0].Text != "Signed {{ signature }}" {
+		t.Fatalf("image pages = %+v", extracted.Pages)
+	}
+	if images := recognizer.Images(); len(images) != 3 {
+		t.Fatalf("OCR got %v, want the image too", images)
+	}
+
+	if _, err := d.extractFile(ctx, model.PLAIN_TEXT, []byte("Hi {{ name }}")); err != nil {
+		t.Fatal(err)
+	}
+	if images := recognizer.Images(); len(images) != 3 {
+		t.Fatalf("OCR got %v, text files must not go through OCR", images)
+	}
+}
+
+func TestLocateOrder(t *testing.T) {
+	pages := []lint.Page{
+		{Text: "Dear {{ name }},\nyour total is {{ total | currency \"EUR\" }}.\n{{ name }}, pay by {{ due_date }} {{ total }}"},
+	}
+
+	want := []dto.Variable{
+		{Name: "name", Count: 2, Positions: []dto.Position{{Line: 1, Column: 6}, {Line: 3, Column: 1}}},
+		{Name: "total", Count: 2, Positions: []dto.Position{{Line: 2, Column: 15}, {Line: 3, Column: 35}}},
+		{Name: "due_date", Count: 1, Positions: []dto.Position{{Line: 3, Column: 20}}},
+	}
+
+	// The order must not depend on map iteration, so that responses and snapshots are stable.
+	for range 20 {
+		if got := locate(pages); !reflect.DeepEqual(got, want) {
+			t.Fatalf("locate = %+v, want %+v", got, want)
+		}
+	}
+
+	pdf := []lint.Page{
+		{Number: 1, Text: "{{ b }} {{ a }}"},
+		{Number: 2, Text: "{{ c }} {{ a }} {{ b }}"},
+	}
+	if got := variableNames(locate(pdf)); !slices.Equal(got, []string{"b", "a", "c"}) {
+		t.Fatalf("PDF variables = %v, want b, a, c", got)
+	}
+	if got := locate(pdf)[1].Positions; !reflect.DeepEqual(got, []dto.Position{{Page: 1}, {Page: 2}}) {
+		t.Fatalf("positions of a = %+v", got)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/render"
+)
+
+// expand returns the text of a TEXT template with its layout applied and the templates it
+// includes inlined, recursively. Layouts and includes are resolved at every call, so that
+// changes to them are picked up by the templates that use them.
+func (d *documentService) expand(ctx context.Context, doc *model.Document) (string, error) {
+	text, err := d.inherit(ctx, doc)
+	if err != nil {
+		return "", err
+	}
+
+	e := &expansion{
+		d:           d,
+		contentType: doc.ContentType,
+		docs:        map[string]*model.Document{},
+		texts:       map[string]expanded{},
+	}
+	text, _, err = e.expandText(ctx, text, []string{doc.ID.Hex()})
+	return text, err
+}
+
+// expansion resolves the includes of one template. Each reference is looked up once and each
+// included template expanded once, however many times it is included, and every expanded
+// text is bounded by includeBytes, so that templates including others many times over cannot
+// make the expansion grow exponentially.
+type expansion struct {
+	d *documentService
+
+	// contentType is that of the expanded template, which included templates must share:
+	// PLAIN_TEXT bodies are not sanitized, and would be inlined as markup.
+	contentType model.ContentType
+
+	// docs holds the templates resolved by reference, texts the expanded templates by ID.
+	docs  map[string]*model.Document
+	texts map[string]expanded
+}
+
+type expanded struct {
+	text string
+
+	// depth is how deeply the includes of the template are nested, 0 if it includes none.
+	depth int
+}
+
+// expandText resolves the includes of text, where stack lists the IDs of the templates being
+// expanded, outermost first, and returns how deeply they are nested.
+func (e *expansion) expandText(ctx context.Context, text string, stack []string) (string, int, error) {
+	depth := 0
+
+	result, err := render.ExpandIncludes(text, e.d.includeBytes, func(ref string) (string, error) {
+		if len(stack) > e.d.includeDepth {
+			return "", fmt.Errorf("%w %q: includes are nested more than %d deep", ErrInclude, ref, e.d.includeDepth)
+		}
+
+		included, err := e.resolve(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+
+		ID := included.ID.Hex()
+		if slices.Contains(stack, ID) {
+			return "", fmt.Errorf("%w %q: include cycle %s", ErrInclude, ref, strings.Join(append(slices.Clone(stack), ID), " -> "))
+		}
+
+		x, ok := e.texts[ID]
+		if !ok {
+			x.text, x.depth, err = e.expandText(ctx, *included.Body.Text, append(slices.Clone(stack), ID))
+			if err != nil {
+				return "", err
+			}
+			e.texts[ID] = x
+		}
+
+		// A template expanded before, nested less deeply, may be nested too deeply here.
+		if len(stack)+x.depth > e.d.includeDepth {
+			return "", fmt.Errorf("%w %q: includes are nested more than %d deep", ErrInclude, ref, e.d.includeDepth)
+		}
+
+		depth = max(depth, x.depth+1)
+		return x.text, nil
+	})
+	if errors.Is(err, render.ErrTooLarge) {
+		return "", 0, fmt.Errorf("%w: %v", ErrInclude, err)
+	}
+	return result, depth, err
+}
+
+// resolve finds and localizes the template included as ref, checking that it can be included.
+func (e *expansion) resolve(ctx context.Context, ref string) (*model.Document, error) {
+	if included, ok := e.docs[ref]; ok {
+		return included, nil
+	}
+
+	included, err := e.d.resolveTemplate(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInclude, ref, err)
+	}
+
+	localize(ctx, included)
+
+	if included.Source != model.TEXT || included.Body == nil || included.Body.Text == nil {
+		return nil, fmt.Errorf("%w %q: only TEXT documents can be included", ErrInclude, ref)
+	}
+	if included.ContentType != e.contentType {
+		return nil, fmt.Errorf("%w %q: included template is %s, not %s", ErrInclude, ref, included.ContentType, e.contentType)
+	}
+
+	e.docs[ref] = included
+	return included, nil
+}
+
+// resolveTemplate finds a template by ID, alias or slug, or else by name.
+func (d *documentService) resolveTemplate(ctx context.Context, ref string) (*model.Document, error) {
+	doc, err := d.find(ctx, ref)
+	if err == nil || errors.Is(err, ErrNotPublished) {
+		return doc, err
+	}
+
+	doc, err = d.repo.FindByName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := visible(ctx, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
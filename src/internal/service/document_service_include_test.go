@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/helpers"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/render"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// includeService stores one TEXT template per name, with the given content type and text.
+func includeService(templates map[string]string, contentTypes map[string]model.ContentType) (*documentService, *memoryDocuments) {
+	documents := &memoryDocuments{docs: map[primitive.ObjectID]*model.Document{}}
+	for name, text := range templates {
+		contentType, ok := contentTypes[name]
+		if !ok {
+			contentType = model.HTML
+		}
+		doc := &model.Document{
+			ID:          primitive.NewObjectID(),
+			Name:        name,
+			Type:        model.TEMPLATE,
+			Source:      model.TEXT,
+			ContentType: contentType,
+			Body:        &model.DocumentBody{Text: &text},
+		}
+		documents.docs[doc.ID] = doc
+	}
+
+	return &documentService{repo: documents, mapper: helpers.NewDocumentMapper(), includeDepth: 10}, documents
+}
+
+func expandNamed(t *testing.T, d *documentService, documents *memoryDocuments, name string) (string, error) {
+	t.Helper()
+	doc, err := documents.FindByName(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	documents.lookups = 0
+	return d.expand(context.Background(), doc)
+}
+
+func TestIncludeContentType(t *testing.T) {
+	d, documents := includeService(map[string]string{
+		"page":   `<p>{{> header }}</p>`,
+		"mail":   `<p>{{> notes }}</p>`,
+		"header": `<h1>{{ title }}</h1>`,
+		"notes":  `<script>alert(1)</script>`,
+	}, map[string]model.ContentType{"notes": model.PLAIN_TEXT})
+
+	text, err := expandNamed(t, d, documents, "page")
+	if err != nil || text != `<p><h1>{{ title }}</h1></p>` {
+		t.Fatalf("HTML include: %q, %v", text, err)
+	}
+
+	if _, err := expandNamed(t, d, documents, "mail"); !errors.Is(err, ErrInclude) || !strings.Contains(err.Error(), "PLAIN_TEXT") {
+		t.Fatalf("PLAIN_TEXT included in HTML: err = %v, want ErrInclude", err)
+	}
+}
+
+func TestIncludeFanOut(t *testing.T) {
+	// Every level includes the next one ten times, so that the expansion of level0 would be
+	// 10^8 times the size of level8 and take as many lookups without memoization.
+	templates := map[string]string{"level8": "x"}
+	for i := 7; i >= 0; i-- {
+		templates[fmt.Sprintf("level%d", i)] = strings.Repeat(fmt.Sprintf("{{> level%d }}", i+1), 10)
+	}
+
+	d, documents := includeService(templates, nil)
+	d.includeBytes = 1 << 20
+
+	text, err := expandNamed(t, d, documents, "level3")
+	if err != nil || text != strings.Repeat("x", 100000) {
+		t.Fatalf("level3 expanded to %d bytes, %v", len(text), err)
+	}
+	if documents.lookups > 2*5 {
+		t.Fatalf("%d lookups for 5 distinct includes", documents.lookups)
+	}
+
+	if _, err := expandNamed(t, d, documents, "level0"); !errors.Is(err, ErrInclude) || !strings.Contains(err.Error(), render.ErrTooLarge.Error()) {
+		t.Fatalf("level0: err = %v, want ErrInclude for its size", err)
+	}
+	if documents.lookups > 2*8 {
+		t.Fatalf("%d lookups for 8 distinct includes", documents.lookups)
+	}
+}
+
+func TestIncludeDepthWithMemoizedIncludes(t *testing.T) {
+	// b is expanded at the first level, then included again from the second through c, where
+	// its own include is nested one level too deep.
+	d, documents := includeService(map[string]string{
+		"top":  `{{> b }}{{> c }}`,
+		"c":    `{{> b }}`,
+		"b":    `{{> leaf }}`,
+		"leaf": `leaf`,
+	}, nil)
+	d.includeDepth = 2
+
+	if _, err := expandNamed(t, d, documents, "top"); !errors.Is(err, ErrInclude) || !strings.Contains(err.Error(), "nested more than 2") {
+		t.Fatalf("err = %v, want ErrInclude for the depth", err)
+	}
+
+	d.includeDepth = 3
+	if text, err := expandNamed(t, d, documents, "top"); err != nil || text != "leafleaf" {
+		t.Fatalf("depth 3: %q, %v", text, err)
+	}
+}
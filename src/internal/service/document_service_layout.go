@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/render"
+)
+
+// inherit returns the text of a TEXT template with the layouts it extends applied. The blocks
+// of doc override those of its layout, which override those of the layout it extends in turn,
+// up to a template extending no layout, whose text is filled in with the overrides.
+func (d *documentService) inherit(ctx context.Context, doc *model.Document) (string, error) {
+	overrides := map[string]string{}
+	chain := []string{doc.ID.Hex()}
+
+	for doc.Layout != "" {
+		blocks, err := render.Blocks(*doc.Body.Text)
+		if err != nil {
+			return "", fmt.Errorf("%w %q: %v", ErrLayout, doc.Name, err)
+		}
+		for name, body := range blocks {
+			if _, ok := overrides[name]; !ok {
+				overrides[name] = body
+			}
+		}
+
+		ref := doc.Layout
+		if len(chain) > d.includeDepth {
+			return "", fmt.Errorf("%w %q: layouts are nested more than %d deep", ErrLayout, ref, d.includeDepth)
+		}
+
+		layout, err := d.resolveTemplate(ctx, ref)
+		if err != nil {
+			return "", fmt.Errorf("%w %q: %v", ErrLayout, ref, err)
+		}
+
+		localize(ctx, layout)
+
+		ID := layout.ID.Hex()
+		if slices.Contains(chain, ID) {
+			return "", fmt.Errorf("%w %q: layout cycle %s", ErrLayout, ref, strings.Join(append(chain, ID), " -> "))
+		}
+
+		if layout.Type != model.TEMPLATE || layout.Source != model.TEXT || layout.Body == nil || layout.Body.Text == nil {
+			return "", fmt.Errorf("%w %q: only TEXT templates can be layouts", ErrLayout, ref)
+		}
+		if layout.ContentType != doc.ContentType {
+			return "", fmt.Errorf("%w %q: layout is %s, not %s", ErrLayout, ref, layout.ContentType, doc.ContentType)
+		}
+
+		chain = append(chain, ID)
+		doc = layout
+	}
+
+	text, err := render.ApplyLayout(*doc.Body.Text, overrides)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %v", ErrLayout, doc.Name, err)
+	}
+	return text, nil
+}
+
+// composed reports whether the text of doc depends on other templates, through its layout or
+// includes, and so may change without doc being updated.
+func composed(doc *model.Document) bool {
+	if doc.Layout != "" {
+		return true
+	}
+	return doc.Body != nil && doc.Body.Text != nil && render.HasIncludes(*doc.Body.Text)
+}
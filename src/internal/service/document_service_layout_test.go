@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+)
+
+// withLayouts sets the layout of the named templates.
+func withLayouts(documents *memoryDocuments, layouts map[string]string) {
+	for _, doc := range documents.docs {
+		doc.Layout = layouts[doc.Name]
+	}
+}
+
+func TestLayoutChain(t *testing.T) {
+	d, documents := includeService(map[string]string{
+		"base":    `<html><head>{{ block "title" }}<title>Site</title>{{ endblock }}</head><body>{{ block "body" }}{{ endblock }}</body></html>`,
+		"section": `{{ block "body" }}<nav>Docs</nav><main>{{ block "content" }}<p>Empty</p>{{ endblock }}</main>{{ endblock }}`,
+		"page":    `{{ block "title" }}<title>{{ title }}</title>{{ endblock }}{{ block "content" }}<p>{{ name }}</p>{{ endblock }}`,
+		"empty":   `{{ block "title" }}<title>Empty</title>{{ endblock }}`,
+	}, nil)
+	withLayouts(documents, map[string]string{"page": "section", "empty": "section", "section": "base"})
+
+	text, err := expandNamed(t, d, documents, "page")
+	if want := `<html><head><title>{{ title }}</title></head><body><nav>Docs</nav><main><p>{{ name }}</p></main></body></html>`; err != nil || text != want {
+		t.Fatalf("page: %q, %v", text, err)
+	}
+
+	// Blocks a template leaves out keep the content of the nearest layout defining them.
+	text, err = expandNamed(t, d, documents, "empty")
+	if want := `<html><head><title>Empty</title></head><body><nav>Docs</nav><main><p>Empty</p></main></body></html>`; err != nil || text != want {
+		t.Fatalf("empty: %q, %v", text, err)
+	}
+}
+
+func TestLayoutChainErrors(t *testing.T) {
+	d, documents := includeService(map[string]string{
+		"a":     `{{ block "x" }}a{{ endblock }}`,
+		"b":     `{{ block "x" }}b{{ endblock }}`,
+		"text":  `{{ block "x" }}text{{ endblock }}`,
+		"page":  `{{ block "x" }}page{{ endblock }}`,
+		"open":  `{{ block "x" }}open`,
+		"plain": `{{ block "x" }}plain{{ endblock }}`,
+	}, map[string]model.ContentType{"text": model.PLAIN_TEXT})
+	withLayouts(documents, map[string]string{"a": "b", "b": "a", "page": "text", "open": "a", "plain": "missing"})
+
+	for name, want := range map[string]string{
+		"a":     "layout cycle",
+		"page":  "layout is PLAIN_TEXT, not HTML",
+		"open":  `block "x" is not closed`,
+		"plain": `"missing"`,
+	} {
+		if _, err := expandNamed(t, d, documents, name); !errors.Is(err, ErrLayout) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want ErrLayout with %q", name, err, want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
+)
+
+const maxCommentLength = 1024
+
+// TransitionTemplate moves template ID to another lifecycle state, recording who did it.
+// Publishing a document in review, and archiving a published one, require the publish
+// permission; publishing records the approver, who cannot be the user who submitted it.
+func (d *documentService) TransitionTemplate(ctx context.Context, ID string, payload *dto.TransitionRequest) (*dto.Document, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID), slog.String("state", string(payload.State)))
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.TransitionTemplate", "status", "started")
+
+	principal := auth.FromContext(ctx)
+	if principal.Subject == "" {
+		err := fmt.Errorf("%w: lifecycle transitions require a user", ErrForbidden)
+		logger.ErrorContext(ctx, "DocumentService.TransitionTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	if !payload.State.IsValid() || len(payload.Comment) > maxCommentLength {
+		err := fmt.Errorf("%w: state must be one of DRAFT, IN_REVIEW, PUBLISHED or ARCHIVED, with a comment of at most %d characters", ErrTransition, maxCommentLength)
+		logger.ErrorContext(ctx, "DocumentService.TransitionTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	doc, err := d.find(ctx, ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.TransitionTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	from := doc.State.Current()
+	if !from.CanTransition(payload.State) {
+		err := fmt.Errorf("%w: from %s to %s", ErrTransition, from, payload.State)
+		logger.ErrorContext(ctx, "DocumentService.TransitionTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	if (payload.State == model.PUBLISHED || from == model.PUBLISHED) && !principal.Can(auth.PermissionPublish) {
+		err := fmt.Errorf("%w: moving from %s to %s requires the %s permission", ErrForbidden, from, payload.State, auth.PermissionPublish)
+		logger.ErrorContext(ctx, "DocumentService.TransitionTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	if from == model.IN_REVIEW && payload.State == model.PUBLISHED && submitter(doc) == principal.Subject {
+		err := fmt.Errorf("%w: %s submitted the document for review and cannot approve it", ErrForbidden, principal.Subject)
+		logger.ErrorContext(ctx, "DocumentService.TransitionTemplate", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	doc.State = payload.State
+	doc.Transitions = append(doc.Transitions, model.Transition{
+		From:    from,
+		To:      payload.State,
+		By:      principal.Subject,
+		Comment: strings.TrimSpace(payload.Comment),
+		At:      now,
+	})
+
+	// An approval covers the content that was reviewed, so drafts lose it.
+	switch payload.State {
+	case model.PUBLISHED:
+		doc.ApprovedBy = principal.Subject
+		doc.ApprovedAt = &now
+	case model.DRAFT:
+		doc.ApprovedBy = ""
+		doc.ApprovedAt = nil
+	}
+
+	updated, err := d.repo.UpdateOne(ctx, doc)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.TransitionTemplate", "status", "failure", "step", "updating DB", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to update document: %w", err)
+	}
+
+	result, err := d.mapper.ToDTO(updated)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.TransitionTemplate", "status", "failure", "step", "converting to DTO", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to convert to DTO: %w", err)
+	}
+
+	logger.InfoContext(ctx, "DocumentService.TransitionTemplate", "status", "success", "from", from, "by", principal.Subject, "duration", time.Since(start))
+	return result, nil
+}
+
+// submitter returns the user who last submitted doc for review.
+func submitter(doc *model.Document) string {
+	for i := len(doc.Transitions) - 1; i >= 0; i-- {
+		if doc.Transitions[i].To == model.IN_REVIEW {
+			return doc.Transitions[i].By
+		}
+	}
+	return ""
+}
+
+// deletable fails with ErrForbidden unless a user deletes doc and, for documents that went
+// through review and may be in use, holds the publish permission.
+func deletable(principal auth.Principal, doc *model.Document) error {
+	if principal.Subject == "" {
+		return fmt.Errorf("%w: deleting documents requires a user", ErrForbidden)
+	}
+	if state := doc.State.Current(); state != model.DRAFT && !principal.Can(auth.PermissionPublish) {
+		return fmt.Errorf("%w: deleting a %s document requires the %s permission", ErrForbidden, state, auth.PermissionPublish)
+	}
+	return nil
+}
+
+type consumerKey struct{}
+
+// forConsumers marks ctx as serving the consumers of templates, who only see published
+// documents, including through layouts, includes and compositions, unless they preview.
+func forConsumers(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consumerKey{}, true)
+}
+
+// visible fails with ErrNotPublished when ctx serves consumers who do not preview and doc
+// is not published.
+func visible(ctx context.Context, doc *model.Document) error {
+	consumer, _ := ctx.Value(consumerKey{}).(bool)
+	if !consumer || auth.Previewing(ctx) || doc.State.Current() == model.PUBLISHED {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is %s", ErrNotPublished, doc.ID.Hex(), doc.State.Current())
+}
+
+// editable fails with ErrNotDraft unless doc is a draft, the only state in which its
+// content may change.
+func editable(doc *model.Document) error {
+	if state := doc.State.Current(); state != model.DRAFT {
+		return fmt.Errorf("%w: the document is %s", ErrNotDraft, state)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/helpers"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
+	"github.com/antoniofrisenda/template-service/src/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryDocuments keeps documents in memory, implementing the part of the repository the
+// lifecycle and includes use, and counts the lookups.
+type memoryDocuments struct {
+	repository.DocumentRepository
+	docs    map[primitive.ObjectID]*model.Document
+	lookups int
+}
+
+func (m *memoryDocuments) FindOne(ctx context.Context, ID primitive.ObjectID) (*model.Document, error) {
+	m.lookups++
+	doc, ok := m.docs[ID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *doc
+	return &copied, nil
+}
+
+func (m *memoryDocuments) FindBySlug(ctx context.Context, slug string) (*model.Document, error) {
+	m.lookups++
+	return nil, repository.ErrNotFound
+}
+
+func (m *memoryDocuments) FindByName(ctx context.Context, name string) (*model.Document, error) {
+	m.lookups++
+	for _, doc := range m.docs {
+		if doc.Name == name {
+			copied := *doc
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memoryDocuments) UpdateOne(ctx context.Context, doc *model.Document) (*model.Document, error) {
+	m.docs[doc.ID] = doc
+	return doc, nil
+}
+
+func (m *memoryDocuments) DeleteOne(ctx context.Context, ID primitive.ObjectID) error {
+	delete(m.docs, ID)
+	return nil
+}
+
+func lifecycleService(state model.LifecycleState, transitions ...model.Transition) (*documentService, *memoryDocuments, string) {
+	text := "Hello {{ name }}"
+	doc := &model.Document{
+		ID:          primitive.NewObjectID(),
+		Name:        "invoice",
+		Type:        model.TEMPLATE,
+		Source:      model.TEXT,
+		ContentType: model.HTML,
+		State:       state,
+		Transitions: transitions,
+		Body:        &model.DocumentBody{Text: &text},
+	}
+
+	documents := &memoryDocuments{docs: map[primitive.ObjectID]*model.Document{doc.ID: doc}}
+	return &documentService{repo: documents, mapper: helpers.NewDocumentMapper()}, documents, doc.ID.Hex()
+}
+
+func as(subject string, permissions ...string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Subject: subject, Permissions: permissions})
+}
+
+func TestTransitionRejectsSelfApproval(t *testing.T) {
+	d, _, ID := lifecycleService(model.IN_REVIEW, model.Transition{From: model.DRAFT, To: model.IN_REVIEW, By: "ann"})
+	publish := &dto.TransitionRequest{State: model.PUBLISHED}
+
+	if _, err := d.TransitionTemplate(as("ann", auth.PermissionPublish), ID, publish); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("self approval: err = %v, want ErrForbidden", err)
+	}
+
+	result, err := d.TransitionTemplate(as("bob", auth.PermissionPublish), ID, publish)
+	if err != nil {
+		t.Fatalf("approval by a reviewer: %v", err)
+	}
+	if result.State != model.PUBLISHED || result.ApprovedBy != "bob" {
+		t.Fatalf("state = %s, approved by %q", result.State, result.ApprovedBy)
+	}
+}
+
+func TestTransitionResubmission(t *testing.T) {
+	// Bob sent Ann's submission back and resubmitted it himself: only Ann may approve it now.
+	d, _, ID := lifecycleService(model.IN_REVIEW,
+		model.Transition{From: model.DRAFT, To: model.IN_REVIEW, By: "ann"},
+		model.Transition{From: model.IN_REVIEW, To: model.DRAFT, By: "bob"},
+		model.Transition{From: model.DRAFT, To: model.IN_REVIEW, By: "bob"},
+	)
+	publish := &dto.TransitionRequest{State: model.PUBLISHED}
+
+	if _, err := d.TransitionTemplate(as("bob", auth.PermissionPublish), ID, publish); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("approval by the last submitter: err = %v, want ErrForbidden", err)
+	}
+	if _, err := d.TransitionTemplate(as("ann", auth.PermissionPublish), ID, publish); err != nil {
+		t.Fatalf("approval by the first submitter: %v", err)
+	}
+}
+
+func TestDeleteTemplatePermissions(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		state   model.LifecycleState
+		ctx     context.Context
+		allowed bool
+	}{
+		{"anonymous draft", model.DRAFT, context.Background(), false},
+		{"draft", model.DRAFT, as("ann"), true},
+		{"in review", model.IN_REVIEW, as("ann"), false},
+		{"published", model.PUBLISHED, as("ann"), false},
+		{"legacy published", "", as("ann"), false},
+		{"archived", model.ARCHIVED, as("ann"), false},
+		{"published by a publisher", model.PUBLISHED, as("bob", auth.PermissionPublish), true},
+	} {
+		d, documents, ID := lifecycleService(tc.state)
+
+		err := d.DeleteTemplate(tc.ctx, ID)
+		if tc.allowed && err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if !tc.allowed && !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s: err = %v, want ErrForbidden", tc.name, err)
+		}
+		if deleted := len(documents.docs) == 0; deleted != tc.allowed {
+			t.Errorf("%s: deleted = %v", tc.name, deleted)
+		}
+	}
+}
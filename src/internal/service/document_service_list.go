@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// ListTemplates lists the documents of the tenant matching query in creation order. The
+// cursor of a page is the ID of its last document; one more document than asked is fetched
+// to tell whether another page follows.
+func (d *documentService) ListTemplates(ctx context.Context, query *dto.DocumentQuery) (*dto.DocumentPage, error) {
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.ListTemplates", "status", "started")
+
+	limit := listLimit(query.Limit)
+	filter := documentFilter(query)
+	filter.Limit = limit + 1
+
+	if query.Cursor != "" {
+		after, err := primitive.ObjectIDFromHex(query.Cursor)
+		if err != nil {
+			err = fmt.Errorf("%w: cursor %q", ErrQuery, query.Cursor)
+			logger.ErrorContext(ctx, "DocumentService.ListTemplates", "status", "failure", "error", err, "duration", time.Since(start))
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	docs, err := d.repo.List(ctx, filter)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.ListTemplates", "status", "failure", "step", "querying DB", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	page := &dto.DocumentPage{Documents: make([]dto.DocumentSummary, 0, min(len(docs), limit))}
+	if len(docs) > limit {
+		docs = docs[:limit]
+		page.Next = docs[limit-1].ID.Hex()
+	}
+	for i := range docs {
+		page.Documents = append(page.Documents, d.mapper.ToSummary(&docs[i]))
+	}
+
+	logger.InfoContext(ctx, "DocumentService.ListTemplates", "status", "success", "count", len(page.Documents), "duration", time.Since(start))
+	return page, nil
+}
+
+// listLimit applies the default and the maximum number of documents listed at once.
+func listLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultListLimit
+	case limit > maxListLimit:
+		return maxListLimit
+	default:
+		return limit
+	}
+}
+
+func documentFilter(query *dto.DocumentQuery) model.DocumentFilter {
+	return model.DocumentFilter{
+		Tags:        query.Tags,
+		Category:    query.Category,
+		Metadata:    query.Metadata,
+		Type:        query.Type,
+		Source:      query.Source,
+		ContentType: query.ContentType,
+		State:       query.State,
+	}
+}
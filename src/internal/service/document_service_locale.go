@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"strings"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/locale"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
+)
+
+// PutLocale stores the variant of template ID for a locale, replacing the previous one. The
+// variant must have the type, source and content type of the template, and takes its name,
+// summary and layout. It is sanitised, scanned, linted and extracted like the default body,
+// with layouts and includes resolved in its locale.
+func (d *documentService) PutLocale(ctx context.Context, ID, tag string, payload *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error) {
+	ctx = logging.With(ctx, slog.String("document_id", ID), slog.String("locale", tag))
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.PutLocale", "status", "started")
+
+	tag, err := locale.Normalize(tag)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.PutLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("%w: %v", ErrLocale, err)
+	}
+
+	doc, err := d.find(ctx, ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.PutLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	if err := editable(doc); err != nil {
+		logger.ErrorContext(ctx, "DocumentService.PutLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	if payload.Type != doc.Type || payload.Source != doc.Source || payload.ContentType != doc.ContentType {
+		err := fmt.Errorf("%w: the document is a %s %s of %s", ErrLocale, doc.Type, doc.Source, doc.ContentType)
+		logger.ErrorContext(ctx, "DocumentService.PutLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	if doc.Source == model.TEXT && (payload.Body == nil || payload.Body.Text == nil || strings.TrimSpace(*payload.Body.Text) == "") {
+		err := fmt.Errorf("%w: text source requires non-empty text in body", ErrLocale)
+		logger.ErrorContext(ctx, "DocumentService.PutLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	variant, err := d.mapper.ToModel(&dto.InsertDocument{
+		Name:        doc.Name,
+		Summary:     doc.Summary,
+		Type:        doc.Type,
+		Source:      doc.Source,
+		ContentType: doc.ContentType,
+		Layout:      doc.Layout,
+		Body:        payload.Body,
+	})
+	if err != nil || variant == nil {
+		logger.ErrorContext(ctx, "DocumentService.PutLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to map payload to model: %w", err)
+	}
+
+	variant.ID = doc.ID
+	variant.Body.Locale = tag
+
+	report, err := d.prepare(locale.WithLocales(ctx, []string{tag}), "DocumentService.PutLocale", start, variant, file)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := doc.Locales[tag]
+	if doc.Locales == nil {
+		doc.Locales = map[string]*model.DocumentBody{}
+	}
+	doc.Locales[tag] = variant.Body
+
+	updated, err := d.repo.UpdateOne(ctx, doc)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.PutLocale", "status", "failure", "step", "updating DB", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to update document: %w", err)
+	}
+
+	if url := bodyURL(previous); url != "" && url != bodyURL(variant.Body) {
+		d.deleteFile(ctx, "DocumentService.PutLocale", url)
+	}
+
+	updated.Localize([]string{tag})
+	result, err := d.mapper.ToDTO(updated)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.PutLocale", "status", "failure", "step", "converting to DTO", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to convert to DTO: %w", err)
+	}
+
+	if report != nil && len(report.Issues) > 0 {
+		result.Lint = report
+	}
+	if report != nil {
+		result.Diagnostics = report.Diagnostics
+	}
+
+	logger.InfoContext(ctx, "DocumentService.PutLocale", "status", "success", "duration", time.Since(start))
+	return result, nil
+}
+
+// DeleteLocale removes the variant of template ID for a locale, along with its file.
+func (d *documentService) DeleteLocale(ctx context.Context, ID, tag string) error {
+	ctx = logging.With(ctx, slog.String("document_id", ID), slog.String("locale", tag))
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.DeleteLocale", "status", "started")
+
+	tag, err := locale.Normalize(tag)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.DeleteLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return fmt.Errorf("%w: %v", ErrLocale, err)
+	}
+
+	doc, err := d.find(ctx, ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.DeleteLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return fmt.Errorf("document not found: %w", err)
+	}
+
+	if err := editable(doc); err != nil {
+		logger.ErrorContext(ctx, "DocumentService.DeleteLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return err
+	}
+
+	variant, ok := doc.Locales[tag]
+	if !ok {
+		err := fmt.Errorf("locale %s not found", tag)
+		logger.ErrorContext(ctx, "DocumentService.DeleteLocale", "status", "failure", "error", err, "duration", time.Since(start))
+		return err
+	}
+	delete(doc.Locales, tag)
+
+	if _, err := d.repo.UpdateOne(ctx, doc); err != nil {
+		logger.ErrorContext(ctx, "DocumentService.DeleteLocale", "status", "failure", "step", "updating DB", "error", err, "duration", time.Since(start))
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+
+	if url := bodyURL(variant); url != "" {
+		d.deleteFile(ctx, "DocumentService.DeleteLocale", url)
+	}
+
+	logger.InfoContext(ctx, "DocumentService.DeleteLocale", "status", "success", "duration", time.Since(start))
+	return nil
+}
+
+// localize selects the variant of doc for the locales of the request, following their
+// fallback chain (it-IT, then it) before falling back to the default body.
+func localize(ctx context.Context, doc *model.Document) {
+	doc.Localize(locale.Fallbacks(locale.FromContext(ctx)))
+}
+
+// variantURLs lists the files of the localized variants of doc.
+func variantURLs(doc *model.Document) []string {
+	var urls []string
+	for _, variant := range doc.Locales {
+		if url := bodyURL(variant); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+func bodyURL(body *model.DocumentBody) string {
+	if body == nil || body.URL == nil {
+		return ""
+	}
+	return *body.URL
+}
+
+// deleteFile removes a file no longer referenced, logging rather than failing when it cannot.
+func (d *documentService) deleteFile(ctx context.Context, operation, url string) {
+	d.invalidate(ctx, fileKey(url))
+	if err := d.storage(ctx).Delete(ctx, url); err != nil {
+		logger.WarnContext(ctx, operation, "status", "orphaned", "step", "deleting file", "error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/render"
+	"github.com/antoniofrisenda/template-service/src/internal/search"
+)
+
+const (
+	maxSearchLength = 512
+
+	// bodySnippets bounds the snippets returned for the body of a document; names and
+	// summaries get one.
+	bodySnippets = 3
+)
+
+// SearchTemplates runs a Mongo text search over the names, summaries, TEXT bodies and the
+// text extracted from FILE templates. Words are matched whole, quoted phrases must all be
+// present and words prefixed with a minus exclude documents. Localized variants are not
+// searched.
+func (d *documentService) SearchTemplates(ctx context.Context, text string, query *dto.DocumentQuery) (*dto.SearchResults, error) {
+	start := time.Now()
+	logger.InfoContext(ctx, "DocumentService.SearchTemplates", "status", "started")
+
+	text = strings.TrimSpace(text)
+	terms := search.Terms(text)
+	if len(terms) == 0 || len(text) > maxSearchLength {
+		err := fmt.Errorf("%w: search text must have words and at most %d characters", ErrQuery, maxSearchLength)
+		logger.ErrorContext(ctx, "DocumentService.SearchTemplates", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	filter := documentFilter(query)
+	filter.Limit = listLimit(query.Limit)
+
+	hits, err := d.repo.Search(ctx, text, filter)
+	if err != nil {
+		logger.ErrorContext(ctx, "DocumentService.SearchTemplates", "status", "failure", "step", "querying DB", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to search documents: %w", err)
+	}
+
+	results := &dto.SearchResults{Query: text, Results: make([]dto.SearchHit, 0, len(hits))}
+	for i := range hits {
+		hit := &hits[i]
+		results.Results = append(results.Results, dto.SearchHit{
+			Document:   d.mapper.ToSummary(&hit.Document),
+			Score:      hit.Score,
+			Highlights: highlights(&hit.Document, terms),
+		})
+	}
+
+	logger.InfoContext(ctx, "DocumentService.SearchTemplates", "status", "success", "count", len(results.Results), "duration", time.Since(start))
+	return results, nil
+}
+
+// highlights returns the snippets of the fields of doc where terms occur.
+func highlights(doc *model.Document, terms []string) []dto.Highlight {
+	fields := []struct {
+		name  string
+		text  string
+		limit int
+	}{
+		{"name", doc.Name, 1},
+		{"summary", doc.Summary, 1},
+		{"body", searchableBody(doc), bodySnippets},
+	}
+
+	var result []dto.Highlight
+	for _, f := range fields {
+		if snippets := search.Highlight(f.text, terms, f.limit); len(snippets) > 0 {
+			result = append(result, dto.Highlight{Field: f.name, Snippets: snippets})
+		}
+	}
+	return result
+}
+
+// searchableBody returns the text of the body of doc as searched: the visible text of HTML,
+// the text of other TEXT bodies and the extracted text of files.
+func searchableBody(doc *model.Document) string {
+	switch {
+	case doc.Body == nil:
+		return ""
+	case doc.Source == model.FILE:
+		return doc.Body.Content
+	case doc.Body.Text == nil:
+		return ""
+	case doc.ContentType == model.HTML:
+		return render.HTMLText(*doc.Body.Text)
+	default:
+		return *doc.Body.Text
+	}
+}
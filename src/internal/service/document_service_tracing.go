@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/antoniofrisenda/template-service/src/internal/service")
+
+type tracedDocumentService struct {
+	next DocumentService
+}
+
+// NewTracedDocumentService wraps every DocumentService method in a span.
+func NewTracedDocumentService(next DocumentService) DocumentService {
+	return &tracedDocumentService{next: next}
+}
+
+func (t *tracedDocumentService) ExtractVariables(ctx context.Context, ID string, refresh bool) (*dto.ExtractedVariables, error) {
+	ctx, span := startSpan(ctx, "DocumentService.ExtractVariables", attribute.String("document.id", ID), attribute.Bool("refresh", refresh))
+	defer span.End()
+
+	variables, err := t.next.ExtractVariables(ctx, ID, refresh)
+	return variables, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) LocateVariables(ctx context.Context, ID string) (*dto.ExtractedVariables, error) {
+	ctx, span := startSpan(ctx, "DocumentService.LocateVariables", attribute.String("document.id", ID))
+	defer span.End()
+
+	variables, err := t.next.LocateVariables(ctx, ID)
+	return variables, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) ReextractVariables(ctx context.Context) (int, error) {
+	ctx, span := startSpan(ctx, "DocumentService.ReextractVariables", attribute.Int("parser.version", ParserVersion))
+	defer span.End()
+
+	updated, err := t.next.ReextractVariables(ctx)
+	span.SetAttributes(attribute.Int("documents.updated", updated))
+	return updated, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) FindTemplate(ctx context.Context, ID string) (*dto.Document, error) {
+	ctx, span := startSpan(ctx, "DocumentService.FindTemplate", attribute.String("document.id", ID))
+	defer span.End()
+
+	result, err := t.next.FindTemplate(ctx, ID)
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) FindTemplateWithPresignedURL(ctx context.Context, ID string) (string, error) {
+	ctx, span := startSpan(ctx, "DocumentService.FindTemplateWithPresignedURL", attribute.String("document.id", ID))
+	defer span.End()
+
+	url, err := t.next.FindTemplateWithPresignedURL(ctx, ID)
+	return url, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) ListTemplates(ctx context.Context, query *dto.DocumentQuery) (*dto.DocumentPage, error) {
+	ctx, span := startSpan(ctx, "DocumentService.ListTemplates")
+	defer span.End()
+
+	result, err := t.next.ListTemplates(ctx, query)
+	if result != nil {
+		span.SetAttributes(attribute.Int("documents.count", len(result.Documents)))
+	}
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) SearchTemplates(ctx context.Context, text string, query *dto.DocumentQuery) (*dto.SearchResults, error) {
+	ctx, span := startSpan(ctx, "DocumentService.SearchTemplates")
+	defer span.End()
+
+	result, err := t.next.SearchTemplates(ctx, text, query)
+	if result != nil {
+		span.SetAttributes(attribute.Int("documents.count", len(result.Results)))
+	}
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) TransitionTemplate(ctx context.Context, ID string, payload *dto.TransitionRequest) (*dto.Document, error) {
+	ctx, span := startSpan(ctx, "DocumentService.TransitionTemplate",
+		attribute.String("document.id", ID),
+		attribute.String("document.state", string(payload.State)),
+	)
+	defer span.End()
+
+	result, err := t.next.TransitionTemplate(ctx, ID, payload)
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) InsertTemplate(ctx context.Context, d *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error) {
+	ctx, span := startSpan(ctx, "DocumentService.InsertTemplate", attribute.String("document.content_type", string(d.ContentType)))
+	defer span.End()
+
+	result, err := t.next.InsertTemplate(ctx, d, file)
+	if result != nil {
+		span.SetAttributes(attribute.String("document.id", result.ID))
+	}
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) UpdateTemplate(ctx context.Context, ID string, d *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error) {
+	ctx, span := startSpan(ctx, "DocumentService.UpdateTemplate", attribute.String("document.id", ID))
+	defer span.End()
+
+	result, err := t.next.UpdateTemplate(ctx, ID, d, file)
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) LintTemplate(ctx context.Context, d *dto.InsertDocument, file *multipart.FileHeader) (*dto.LintReport, error) {
+	ctx, span := startSpan(ctx, "DocumentService.LintTemplate", attribute.String("document.content_type", string(d.ContentType)))
+	defer span.End()
+
+	report, err := t.next.LintTemplate(ctx, d, file)
+	if report != nil {
+		span.SetAttributes(attribute.Int("lint.issues", len(report.Issues)), attribute.Bool("lint.blocking", report.Blocking))
+	}
+	return report, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) DeleteTemplate(ctx context.Context, ID string) error {
+	ctx, span := startSpan(ctx, "DocumentService.DeleteTemplate", attribute.String("document.id", ID))
+	defer span.End()
+
+	return endSpan(span, t.next.DeleteTemplate(ctx, ID))
+}
+
+func (t *tracedDocumentService) RenderTemplate(ctx context.Context, ID string, values map[string]any) (*dto.RenderedDocument, error) {
+	ctx, span := startSpan(ctx, "DocumentService.RenderTemplate", attribute.String("document.id", ID))
+	defer span.End()
+
+	result, err := t.next.RenderTemplate(ctx, ID, values)
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) RenderBatch(ctx context.Context, ID string, rows []map[string]any) (*dto.BatchResult, io.ReadCloser, error) {
+	ctx, span := startSpan(ctx, "DocumentService.RenderBatch", attribute.String("document.id", ID), attribute.Int("batch.rows", len(rows)))
+	defer span.End()
+
+	result, archive, err := t.next.RenderBatch(ctx, ID, rows)
+	if result != nil {
+		span.SetAttributes(attribute.Int("batch.failed", result.Failed))
+	}
+	return result, archive, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) StoreBatch(ctx context.Context, ID string, rows []map[string]any) (*dto.BatchResult, error) {
+	ctx, span := startSpan(ctx, "DocumentService.StoreBatch", attribute.String("document.id", ID), attribute.Int("batch.rows", len(rows)))
+	defer span.End()
+
+	result, err := t.next.StoreBatch(ctx, ID, rows)
+	if result != nil {
+		span.SetAttributes(attribute.Int("batch.failed", result.Failed))
+	}
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) ComposeDocuments(ctx context.Context, payload *dto.ComposeRequest) ([]byte, error) {
+	ctx, span := startSpan(ctx, "DocumentService.ComposeDocuments", attribute.Int("compose.documents", len(payload.Documents)))
+	defer span.End()
+
+	result, err := t.next.ComposeDocuments(ctx, payload)
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) PutLocale(ctx context.Context, ID, locale string, payload *dto.InsertDocument, file *multipart.FileHeader) (*dto.Document, error) {
+	ctx, span := startSpan(ctx, "DocumentService.PutLocale", attribute.String("document.id", ID), attribute.String("document.locale", locale))
+	defer span.End()
+
+	result, err := t.next.PutLocale(ctx, ID, locale, payload, file)
+	return result, endSpan(span, err)
+}
+
+func (t *tracedDocumentService) DeleteLocale(ctx context.Context, ID, locale string) error {
+	ctx, span := startSpan(ctx, "DocumentService.DeleteLocale", attribute.String("document.id", ID), attribute.String("document.locale", locale))
+	defer span.End()
+
+	return endSpan(span, t.next.DeleteLocale(ctx, ID, locale))
+}
+
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/repository"
+)
+
+var (
+	ErrInfected     = errors.New("file rejected by malware scan")
+	ErrNotScanned   = errors.New("document is not available until its malware scan is clean")
+	ErrScanFailure  = errors.New("malware scan failed")
+	ErrRender       = errors.New("cannot render document")
+	ErrLint         = errors.New("template rejected by lint checks")
+	ErrInvalidJob   = errors.New("invalid render job")
+	ErrInclude      = errors.New("cannot include template")
+	ErrLayout       = errors.New("cannot apply layout")
+	ErrLocale       = errors.New("invalid locale variant")
+	ErrAlias        = errors.New("invalid alias")
+	ErrSlugTaken    = errors.New("slug is already in use")
+	ErrQuery        = errors.New("invalid query")
+	ErrNotPublished = errors.New("document is not published")
+	ErrTransition   = errors.New("lifecycle transition not allowed")
+	ErrNotDraft     = errors.New("only drafts can be edited")
+	ErrForbidden    = errors.New("permission denied")
+
+	// ErrNotFound is returned, wrapped, when no document matches a reference.
+	ErrNotFound = repository.ErrNotFound
+)
+
+// LintError carries the blocking lint report of a rejected template. It matches ErrLint.
+type LintError struct {
+	Report *dto.LintReport
+}
+
+func (e *LintError) Error() string {
+	return fmt.Sprintf("%s: %d issue(s)", ErrLint, len(e.Report.Issues))
+}
+
+func (e *LintError) Unwrap() error {
+	return ErrLint
+}
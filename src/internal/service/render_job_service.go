@@ -0,0 +1,350 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/antoniofrisenda/template-service/src/clients/aws"
+	"github.com/antoniofrisenda/template-service/src/clients/webhook"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/dto"
+	"github.com/antoniofrisenda/template-service/src/internal/assets/model"
+	"github.com/antoniofrisenda/template-service/src/internal/auth"
+	"github.com/antoniofrisenda/template-service/src/internal/locale"
+	"github.com/antoniofrisenda/template-service/src/internal/logging"
+	"github.com/antoniofrisenda/template-service/src/internal/metrics"
+	"github.com/antoniofrisenda/template-service/src/internal/repository"
+	"github.com/antoniofrisenda/template-service/src/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type RenderJobService interface {
+	SubmitRender(ctx context.Context, payload *dto.RenderJobRequest) (*dto.RenderJob, error)
+	FindRender(ctx context.Context, ID string) (*dto.RenderJob, error)
+
+	// Run processes queued jobs until ctx is cancelled, then waits for the jobs in progress.
+	Run(ctx context.Context)
+}
+
+// JobOptions configures the render workers. Lease bounds the time a worker may hold a job
+// before another one claims it again; jobs claimed more than MaxAttempts times fail.
+type JobOptions struct {
+	Workers        int
+	PollInterval   time.Duration
+	Lease          time.Duration
+	MaxAttempts    int
+	ResultLifetime time.Duration
+}
+
+type renderJobService struct {
+	repo      repository.RenderJobRepository
+	documents repository.DocumentRepository
+	aliases   repository.AliasRepository
+	renderer  DocumentService
+	s3        aws.S3Client
+	buckets   map[string]aws.S3Client
+	notifier  webhook.Notifier
+	options   JobOptions
+	wake      chan struct{}
+}
+
+// NewRenderJobService renders jobs through renderer and stores the results in the bucket of
+// their tenant. A nil notifier disables webhooks: jobs with a callback are then rejected.
+// Templates referenced by alias or slug are resolved when the job is submitted.
+func NewRenderJobService(repo repository.RenderJobRepository, documents repository.DocumentRepository, aliases repository.AliasRepository, renderer DocumentService, s3 aws.S3Client, buckets map[string]aws.S3Client, notifier webhook.Notifier, options JobOptions) RenderJobService {
+	return &renderJobService{
+		repo:      repo,
+		documents: documents,
+		aliases:   aliases,
+		renderer:  renderer,
+		s3:        s3,
+		buckets:   buckets,
+		notifier:  notifier,
+		options:   options,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+func (r *renderJobService) SubmitRender(ctx context.Context, payload *dto.RenderJobRequest) (*dto.RenderJob, error) {
+	ctx = forConsumers(ctx)
+	start := time.Now()
+	logger.InfoContext(ctx, "RenderJobService.SubmitRender", "status", "started")
+
+	if payload.DocumentID == "" {
+		err := fmt.Errorf("%w: documentId is required", ErrInvalidJob)
+		logger.ErrorContext(ctx, "RenderJobService.SubmitRender", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	if err := r.validateCallback(ctx, payload.CallbackURL); err != nil {
+		logger.ErrorContext(ctx, "RenderJobService.SubmitRender", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	doc, err := resolve(ctx, r.documents, r.aliases, payload.DocumentID)
+	if err != nil {
+		logger.ErrorContext(ctx, "RenderJobService.SubmitRender", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	// The job renders in the locales of the submitting request, previewing when it did.
+	job := model.NewRenderJob(doc.ID, payload.Variables, payload.CallbackURL)
+	job.Locales = locale.FromContext(ctx)
+	job.Preview = auth.Previewing(ctx)
+
+	job, err = r.repo.InsertOne(ctx, job)
+	if err != nil {
+		logger.ErrorContext(ctx, "RenderJobService.SubmitRender", "status", "failure", "step", "inserting into DB", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("failed to insert render job: %w", err)
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	logger.InfoContext(ctx, "RenderJobService.SubmitRender", "status", "success", "job_id", job.ID.Hex(), "duration", time.Since(start))
+	return r.toDTO(ctx, job), nil
+}
+
+func (r *renderJobService) FindRender(ctx context.Context, ID string) (*dto.RenderJob, error) {
+	ctx = logging.With(ctx, slog.String("job_id", ID))
+	start := time.Now()
+	logger.InfoContext(ctx, "RenderJobService.FindRender", "status", "started")
+
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "RenderJobService.FindRender", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("invalid object id: %w", err)
+	}
+
+	job, err := r.repo.FindOne(ctx, objID)
+	if err != nil {
+		logger.ErrorContext(ctx, "RenderJobService.FindRender", "status", "failure", "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	logger.InfoContext(ctx, "RenderJobService.FindRender", "status", "success", "duration", time.Since(start))
+	return r.toDTO(ctx, job), nil
+}
+
+func (r *renderJobService) Run(ctx context.Context) {
+	logger.InfoContext(ctx, "RenderJobService.Run", "status", "started", "workers", r.options.Workers)
+
+	var wg sync.WaitGroup
+	for range max(1, r.options.Workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	logger.InfoContext(ctx, "RenderJobService.Run", "status", "stopped")
+}
+
+// work claims and processes jobs one at a time. It polls for jobs every PollInterval, or
+// sooner when a job is submitted to this instance.
+func (r *renderJobService) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := r.repo.Claim(ctx, time.Now().Add(r.options.Lease))
+		if err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "RenderJobService.work", "status", "failure", "step", "claiming job", "error", err)
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-r.wake:
+			case <-time.After(r.options.PollInterval):
+			}
+			continue
+		}
+
+		r.process(ctx, job)
+	}
+}
+
+// process renders a claimed job and notifies its webhook. Shutdown does not interrupt the
+// render, which is bounded by the lease instead, so that jobs are not failed half way.
+func (r *renderJobService) process(ctx context.Context, job *model.RenderJob) {
+	start := time.Now()
+
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.options.Lease)
+	defer cancel()
+
+	jobCtx = tenant.WithTenant(jobCtx, job.Tenant)
+	jobCtx = locale.WithLocales(jobCtx, job.Locales)
+	if job.Preview {
+		jobCtx = auth.WithPreview(jobCtx)
+	}
+	jobCtx = logging.With(jobCtx, slog.String("job_id", job.ID.Hex()), slog.String("tenant", job.Tenant))
+
+	jobCtx, span := startSpan(jobCtx, "RenderJobService.process",
+		attribute.String("job.id", job.ID.Hex()),
+		attribute.String("document.id", job.DocumentID.Hex()),
+		attribute.Int("job.attempts", job.Attempts),
+	)
+	defer span.End()
+
+	logger.InfoContext(jobCtx, "RenderJobService.process", "status", "started", "attempt", job.Attempts)
+
+	err := r.render(jobCtx, job)
+	switch {
+	case err == nil:
+		job.Status = model.SUCCEEDED
+		job.Error = ""
+	case job.Attempts < r.options.MaxAttempts && !errors.Is(err, ErrRender):
+		job.Status = model.QUEUED
+		job.Error = err.Error()
+	default:
+		job.Status = model.FAILED
+		job.Error = err.Error()
+	}
+
+	job.LockedUntil = nil
+	if job.Status.IsFinal() {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+	}
+
+	metrics.RenderJobs.WithLabelValues(string(job.Status)).Inc()
+	metrics.RenderJobDuration.Observe(metrics.Since(start))
+
+	if err := r.repo.Save(jobCtx, job); err != nil {
+		logger.ErrorContext(jobCtx, "RenderJobService.process", "status", "failure", "step", "saving job", "error", err, "duration", time.Since(start))
+		_ = endSpan(span, err)
+		return
+	}
+
+	if err != nil {
+		logger.ErrorContext(jobCtx, "RenderJobService.process", "status", "failure", "job_status", job.Status, "error", err, "duration", time.Since(start))
+		_ = endSpan(span, err)
+	} else {
+		logger.InfoContext(jobCtx, "RenderJobService.process", "status", "success", "duration", time.Since(start))
+	}
+
+	if job.Status.IsFinal() && job.Webhook != nil {
+		r.notify(ctx, jobCtx, job)
+	}
+}
+
+// render renders the template of job and uploads the result next to the tenant documents.
+func (r *renderJobService) render(ctx context.Context, job *model.RenderJob) error {
+	if job.Attempts > r.options.MaxAttempts {
+		return fmt.Errorf("%w: gave up after %d attempts", ErrRender, r.options.MaxAttempts)
+	}
+
+	rendered, err := r.renderer.RenderTemplate(ctx, job.DocumentID.Hex(), job.Variables)
+	if err != nil {
+		return err
+	}
+
+	storage := r.storage(ctx)
+	key := fmt.Sprintf("s3://%s/tenants/%s/renders/%s", storage.GetBucket(), job.Tenant, job.ID.Hex())
+
+	if err := storage.Upload(ctx, key, bytes.NewReader([]byte(rendered.Body))); err != nil {
+		return fmt.Errorf("failed to upload render to S3: %w", err)
+	}
+
+	job.ContentType = rendered.ContentType
+	job.ResultURL = &key
+	return nil
+}
+
+// notify delivers the final status of job to its webhook and records the outcome. Unlike
+// the render, retries stop on shutdown.
+func (r *renderJobService) notify(ctx context.Context, jobCtx context.Context, job *model.RenderJob) {
+	payload, err := json.Marshal(r.toDTO(jobCtx, job))
+	if err != nil {
+		logger.ErrorContext(jobCtx, "RenderJobService.notify", "status", "failure", "error", err)
+		return
+	}
+
+	notifyCtx, cancel := context.WithCancel(jobCtx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	attempts, err := r.notifier.Notify(notifyCtx, job.Webhook.URL, payload)
+	job.Webhook.Attempts = attempts
+	if err != nil {
+		job.Webhook.Error = err.Error()
+		logger.WarnContext(jobCtx, "RenderJobService.notify", "status", "failure", "attempts", attempts, "error", err)
+	} else {
+		now := time.Now().UTC()
+		job.Webhook.DeliveredAt = &now
+		logger.InfoContext(jobCtx, "RenderJobService.notify", "status", "success", "attempts", attempts)
+	}
+	metrics.WebhookDeliveries.WithLabelValues(metrics.Result(err)).Inc()
+
+	if err := r.repo.Save(jobCtx, job); err != nil {
+		logger.ErrorContext(jobCtx, "RenderJobService.notify", "status", "failure", "step", "saving job", "error", err)
+	}
+}
+
+func (r *renderJobService) validateCallback(ctx context.Context, callback string) error {
+	if callback == "" {
+		return nil
+	}
+
+	if r.notifier == nil {
+		return fmt.Errorf("%w: webhooks are disabled", ErrInvalidJob)
+	}
+
+	if err := r.notifier.Validate(ctx, callback); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+
+	return nil
+}
+
+// toDTO converts job, presigning its result. A failure to presign only leaves the URL out.
+func (r *renderJobService) toDTO(ctx context.Context, job *model.RenderJob) *dto.RenderJob {
+	result := &dto.RenderJob{
+		ID:          job.ID.Hex(),
+		DocumentID:  job.DocumentID.Hex(),
+		Locales:     job.Locales,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		Error:       job.Error,
+		ContentType: job.ContentType,
+		CreatedAt:   job.CreatedAt,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+	}
+
+	if job.Webhook != nil {
+		result.Webhook = &dto.WebhookStatus{
+			URL:         job.Webhook.URL,
+			Attempts:    job.Webhook.Attempts,
+			Error:       job.Webhook.Error,
+			DeliveredAt: job.Webhook.DeliveredAt,
+		}
+	}
+
+	if job.Status == model.SUCCEEDED && job.ResultURL != nil {
+		link, err := r.storage(ctx).DownloadWithPresignedURL(ctx, *job.ResultURL, r.options.ResultLifetime)
+		if err != nil {
+			logger.WarnContext(ctx, "RenderJobService.toDTO", "status", "failure", "step", "presigning result", "error", err)
+		} else {
+			result.ResultURL = link
+		}
+	}
+
+	return result
+}
+
+func (r *renderJobService) storage(ctx context.Context) aws.S3Client {
+	if client, ok := r.buckets[tenant.Key(ctx)]; ok {
+		return client
+	}
+	return r.s3
+}
@@ -0,0 +1,34 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+type contextKey struct{}
+
+var pattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+func WithTenant(ctx context.Context, ID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, ID)
+}
+
+func FromContext(ctx context.Context) (string, error) {
+	ID, ok := ctx.Value(contextKey{}).(string)
+	if !ok || ID == "" {
+		return "", fmt.Errorf("tenant not found in context")
+	}
+	return ID, nil
+}
+
+// Key returns the tenant stored in ctx, or an empty string when missing.
+// It is meant for routing lookups that fall back to a default.
+func Key(ctx context.Context) string {
+	ID, _ := FromContext(ctx)
+	return ID
+}
+
+func IsValid(ID string) bool {
+	return pattern.MatchString(ID)
+}